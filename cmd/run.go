@@ -1,18 +1,103 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
 	templateparser "github.com/devloperPlatform/devplatform-project-template-parser"
+	"golang.org/x/term"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// keyValueFlags 可重复配置的参数, 格式: 名称=值
+type keyValueFlags []string
+
+func (i *keyValueFlags) String() string {
+	return strings.Join(*i, ",")
+}
+
+func (i *keyValueFlags) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("错误的参数[%s], 格式应为: 名称=值", value)
+	}
+	*i = append(*i, value)
+	return nil
+}
+
+// fileFlags 可重复配置的文件路径
+type fileFlags []string
+
+func (f *fileFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileFlags) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// proxyPasswordEnv 代理认证密码环境变量
+const proxyPasswordEnv = "TPL_PROXY_PASSWORD"
+
+// readProxyPassword 获取代理认证密码, 优先级: 环境变量 > 命令行参数 > 终端输入(配置了用户名且标准输入为终端时)
+func readProxyPassword(username, flagPassword string) (string, error) {
+	if password, ok := os.LookupEnv(proxyPasswordEnv); ok {
+		return password, nil
+	}
+
+	if flagPassword != "" {
+		_, _ = fmt.Fprintf(os.Stderr, "警告: -proxypassword 会暴露在进程列表及shell历史中, 建议使用%s环境变量\n", proxyPasswordEnv)
+		return flagPassword, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if username == "" || !term.IsTerminal(fd) {
+		return "", nil
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "代理[%s]认证密码: ", username)
+	password, err := term.ReadPassword(fd)
+	_, _ = os.Stderr.WriteString("\n")
+	if err != nil {
+		return "", fmt.Errorf("读取代理认证密码失败: %w", err)
+	}
+	return string(password), nil
+}
+
 func main() {
+	var (
+		inputs      keyValueFlags
+		setVars     keyValueFlags
+		setEnvs     keyValueFlags
+		valuesFiles fileFlags
+	)
+	flag.Var(&inputs, "input", "模板输入参数, 格式: 名称=值, 可重复配置, list类型使用逗号分隔")
+	flag.Var(&setVars, "set", "覆盖自定义变量(vars), 格式: 名称=值, 值按YAML规则解析, 可重复配置, 优先级: 模板 < values文件 < TPL_VAR_*/TPL_ENV_*环境变量 < set/setenv")
+	flag.Var(&setEnvs, "setenv", "覆盖环境变量(envs), 格式: 名称=值, 可重复配置")
+	flag.Var(&valuesFiles, "values", "values文件, 格式: `vars: {...}` 与 `envs: {...}`, 可重复配置, 按顺序覆盖")
+	inputsFile := flag.String("inputs", "", "模板输入参数文件(YAML或JSON), 格式: `名称: 值`")
+	profiles := flag.String("profile", "", "启用的配置集(profiles), 多个使用逗号分隔, 按顺序覆盖")
+	schema := flag.Bool("schema", false, "输出模板输入参数的JSON Schema, 不解析模板")
+	lint := flag.Bool("lint", false, "检查模板中的语法错误、未定义的变量、未被引用的自定义变量及始终被忽略的模板, 不解析模板, 存在错误时退出码为1")
 	templateFileName := flag.String("template", "", "要解析的文件模板地址")
 	projectJsonInfo := flag.String("projectinfo", "", "要设置的工程信息")
 	workPath := flag.String("workpath", "", "工作路径, 默认为模板文件所在目录的out目录")
+	proxyUrl := flag.String("proxy", "", "导入模板及动态变量使用的代理地址, 例: http://proxy.example.com:8080")
+	noProxy := flag.String("noproxy", "", "不使用代理的地址, 多个使用逗号分隔")
+	proxyUser := flag.String("proxyuser", "", "代理认证用户名")
+	proxyPassword := flag.String("proxypassword", "", "代理认证密码, 会暴露在进程列表及shell历史中, 建议使用"+proxyPasswordEnv+"环境变量或在终端中输入")
+	recordDir := flag.String("record", "", "录制动态变量的请求与响应至指定目录")
+	replayDir := flag.String("replay", "", "从指定目录回放动态变量的响应, 未录制的请求将失败")
+	mocksFile := flag.String("mocks", "", "动态变量模拟响应文件, 配置的动态变量将不再发起请求")
+	noExec := flag.Bool("noexec", false, "禁止执行模板中的命令(executes)")
+	allowExec := flag.String("allow", "", "允许执行的可执行文件, 多个使用逗号分隔")
+	denyExec := flag.String("deny", "", "禁止执行的可执行文件, 多个使用逗号分隔")
+	noShellMeta := flag.Bool("nometachars", false, "禁止命令中包含shell元字符")
+	confirmExec := flag.Bool("confirm", false, "执行每条命令前进行确认")
+	strict := flag.Bool("strict", false, "严格模式, 模板中获取不存在的键或未定义的envs、vars、remoteVars时报错")
 
 	flag.Parse()
 
@@ -33,8 +118,40 @@ func main() {
 		return
 	}
 
+	if *schema {
+		// 仅读取模板不创建工作路径, 未指定工作路径时导入路径相对于默认工作路径
+		parser := (&templateparser.Parser{WorkerPath: *workPath}).SetOutput(os.Stderr)
+		content, err := parser.InputsSchemaByFilePath(*templateFileName)
+		if err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			os.Exit(1)
+		}
+		fmt.Println(string(content))
+		return
+	}
+
+	if *lint {
+		parser := (&templateparser.Parser{WorkerPath: *workPath}).SetOutput(os.Stderr)
+		issues, err := parser.LintByFilePath(*templateFileName)
+		if err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			os.Exit(1)
+		}
+		hasError := false
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+			if issue.Level == templateparser.LintLevelError {
+				hasError = true
+			}
+		}
+		if hasError {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if workPath == nil || *workPath == "" {
-		*workPath = filepath.Join(filepath.Dir(*templateFileName), "out")
+		*workPath = templateparser.DefaultWorkPath(*templateFileName)
 	}
 
 	var projectInfo *templateparser.ProjectInfo
@@ -59,6 +176,103 @@ func main() {
 		return
 	}
 
+	if *proxyUrl != "" {
+		password, err := readProxyPassword(*proxyUser, *proxyPassword)
+		if err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			return
+		}
+		proxy := &templateparser.ProxyConfig{
+			Url:      *proxyUrl,
+			Username: *proxyUser,
+			Password: password,
+		}
+		if *noProxy != "" {
+			proxy.NoProxy = strings.Split(*noProxy, ",")
+		}
+		parser.SetProxy(proxy)
+	}
+
+	if *recordDir != "" && *replayDir != "" {
+		_, _ = os.Stderr.WriteString("录制(record)与回放(replay)不能同时使用")
+		return
+	} else if *recordDir != "" {
+		parser.SetRecordMode(*recordDir)
+	} else if *replayDir != "" {
+		parser.SetReplayMode(*replayDir)
+	}
+
+	if *mocksFile != "" {
+		if err = parser.LoadRemoteVarMocksByFilePath(*mocksFile); err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			return
+		}
+	}
+
+	if *inputsFile != "" {
+		if err = parser.LoadInputsByFilePath(*inputsFile); err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			return
+		}
+	}
+
+	for _, input := range inputs {
+		kv := strings.SplitN(input, "=", 2)
+		parser.SetInput(kv[0], kv[1])
+	}
+
+	if *profiles != "" {
+		parser.UseProfiles(strings.Split(*profiles, ",")...)
+	}
+
+	for _, valuesFile := range valuesFiles {
+		if err = parser.LoadValuesByFilePath(valuesFile); err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			return
+		}
+	}
+
+	parser.UseEnvironmentOverrides(true)
+	parser.UseStrictMode(*strict)
+
+	for _, setVar := range setVars {
+		if err = parser.SetVarByString(setVar); err != nil {
+			_, _ = os.Stderr.WriteString(err.Error())
+			return
+		}
+	}
+
+	for _, setEnv := range setEnvs {
+		kv := strings.SplitN(setEnv, "=", 2)
+		parser.SetEnv(kv[0], kv[1])
+	}
+
+	if *noExec || *allowExec != "" || *denyExec != "" || *noShellMeta || *confirmExec {
+		policy := &templateparser.ExecutePolicy{
+			Disabled:         *noExec,
+			NoShellMetaChars: *noShellMeta,
+		}
+		if *allowExec != "" {
+			policy.Allow = strings.Split(*allowExec, ",")
+		}
+		if *denyExec != "" {
+			policy.Deny = strings.Split(*denyExec, ",")
+		}
+		if *confirmExec {
+			stdin := bufio.NewReader(os.Stdin)
+			policy.Confirm = func(info *templateparser.ExecConfirmInfo) (bool, error) {
+				_, _ = fmt.Fprintf(os.Stderr, "\n即将在目录[%s]中执行命令:\n%s\n是否执行? [y/N]: ", info.Dir, info.Command)
+				answer, err := stdin.ReadString('\n')
+				if err != nil && answer == "" {
+					return false, err
+				}
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				return answer == "y" || answer == "yes", nil
+			}
+		}
+		parser.SetExecutePolicy(policy)
+	}
+
 	if err = parser.SetOutput(os.Stdout).DecodeByFilePath(*templateFileName, projectInfo); err != nil {
 		_, _ = os.Stderr.WriteString(err.Error())
 		return
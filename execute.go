@@ -0,0 +1,684 @@
+package templateparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// shellModeExec 不使用解释器, 命令直接按参数数组执行
+const shellModeExec = "exec"
+
+var DefaultShellConfig = &ShellConfig{}
+
+func init() {
+	if runtime.GOOS == "windows" {
+		DefaultShellConfig.args = []string{"cmd.exe", "/c"}
+	} else {
+		DefaultShellConfig.args = []string{"bash", "-c"}
+	}
+}
+
+// ShellConfig shell配置, 支持字符串(`bash -c`)、参数数组(`[bash, -eu, -o, pipefail, -c]`)、
+// 按系统区分的map(`{unix: ..., windows: ...}`), 值为 `exec` 时不使用解释器直接执行命令
+type ShellConfig struct {
+	// args 解释器及参数, 命令作为最后一个参数追加; 仅有解释器时命令通过标准输入传递
+	args []string
+	// exec 不使用解释器, 命令按参数数组直接执行
+	exec bool
+}
+
+// NewShellConfig 通过参数数组创建shell配置, 参数为 `exec` 时为无解释器模式
+func NewShellConfig(args ...string) *ShellConfig {
+	s := &ShellConfig{}
+	s.setArgs(args)
+	return s
+}
+
+func (s *ShellConfig) setArgs(args []string) {
+	if len(args) == 1 && strings.ToLower(args[0]) == shellModeExec {
+		s.exec = true
+		s.args = nil
+		return
+	}
+	s.exec = false
+	s.args = args
+}
+
+// IsEmpty 是否未配置
+func (s *ShellConfig) IsEmpty() bool {
+	return s == nil || (!s.exec && len(s.args) == 0)
+}
+
+// String shell配置的描述
+func (s *ShellConfig) String() string {
+	if s.exec {
+		return shellModeExec
+	}
+	marshal, _ := json.Marshal(s.args)
+	return string(marshal)
+}
+
+func (s *ShellConfig) UnmarshalYAML(value *yaml.Node) error {
+	args, err := decodeShellArgs(value)
+	if err != nil {
+		return err
+	}
+
+	if value.Kind != yaml.MappingNode {
+		s.setArgs(args)
+		return nil
+	}
+
+	currentGoos := "unix"
+	if runtime.GOOS == "windows" {
+		currentGoos = "windows"
+	}
+
+	contents := value.Content
+	for i := 0; i+1 < len(contents); i += 2 {
+		k := contents[i]
+		if k.Tag != "!!str" || strings.ToLower(k.Value) != currentGoos {
+			continue
+		}
+
+		if args, err = decodeShellArgs(contents[i+1]); err != nil {
+			return err
+		}
+		s.setArgs(args)
+	}
+
+	if s.IsEmpty() {
+		s.args = DefaultShellConfig.args
+	}
+
+	return nil
+}
+
+// decodeShellArgs 解析字符串或数组形式的参数, map类型返回空
+func decodeShellArgs(value *yaml.Node) ([]string, error) {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		return splitCommandLine(value.Value)
+	case yaml.SequenceNode:
+		args := make([]string, 0, len(value.Content))
+		for _, v := range value.Content {
+			if v.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("行: %d, 列: %d, 错误的参数类型", v.Line, v.Column)
+			}
+			args = append(args, v.Value)
+		}
+		return args, nil
+	case yaml.MappingNode:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("行: %d, 列: %d, 不支持的shell配置类型", value.Line, value.Column)
+	}
+}
+
+// splitCommandLine 按空白拆分命令行, 支持单引号、双引号及反斜杠转义
+func splitCommandLine(str string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range str {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'' && runtime.GOOS != "windows":
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("命令行[%s]引号或转义未结束", str)
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// quoteShellArg 为参数添加shell引号
+func quoteShellArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>()*?[]{}~!#") {
+		return arg
+	}
+
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+	}
+	return `'` + strings.ReplaceAll(arg, `'`, `'\''`) + `'`
+}
+
+// ExecOptions 命令执行参数
+type ExecOptions struct {
+	// Command 命令字符串, shell模式下交给解释器执行, exec模式下按命令行规则拆分为参数数组
+	Command string
+	// Args 命令参数数组, 配置后优先于Command; shell模式下会转义后拼接为命令字符串
+	Args []string
+	// Dir 执行目录
+	Dir string
+	// Env 环境变量
+	Env []string
+	// Timeout 超时时间
+	Timeout time.Duration
+	// Stdout 标准输出
+	Stdout io.Writer
+	// Stderr 错误输出
+	Stderr io.Writer
+}
+
+// argv 获取实际执行的参数数组及shell模式下通过标准输入传递的内容
+func (s *ShellConfig) argv(opts *ExecOptions) (argv []string, stdin string, err error) {
+	if s.exec {
+		argv = opts.Args
+		if len(argv) == 0 {
+			if argv, err = splitCommandLine(opts.Command); err != nil {
+				return nil, "", err
+			}
+		}
+		if len(argv) == 0 {
+			return nil, "", errors.New("要执行的命令为空")
+		}
+		return argv, "", nil
+	}
+
+	shellArgs := s.args
+	if len(shellArgs) == 0 {
+		shellArgs = DefaultShellConfig.args
+	}
+
+	command := opts.Command
+	if len(opts.Args) > 0 {
+		quoted := make([]string, 0, len(opts.Args))
+		for _, arg := range opts.Args {
+			quoted = append(quoted, quoteShellArg(arg))
+		}
+		command = strings.Join(quoted, " ")
+	}
+
+	if len(shellArgs) == 1 {
+		return shellArgs, command, nil
+	}
+
+	argv = make([]string, 0, len(shellArgs)+1)
+	argv = append(argv, shellArgs...)
+	argv = append(argv, command)
+	return argv, "", nil
+}
+
+// commandLine 获取实际执行的命令行描述, 参数按shell规则转义, 通过标准输入传递的命令以 `<<<` 表示
+func (s *ShellConfig) commandLine(opts *ExecOptions) (string, error) {
+	argv, stdin, err := s.argv(opts)
+	if err != nil {
+		return "", err
+	}
+
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		quoted = append(quoted, quoteShellArg(arg))
+	}
+	command := strings.Join(quoted, " ")
+	if stdin != "" {
+		command += " <<< " + quoteShellArg(stdin)
+	}
+	return command, nil
+}
+
+// Exec 执行命令, shell模式下命令作为解释器的最后一个参数(仅有解释器时通过标准输入传递),
+// exec模式下命令按参数数组直接执行
+func (s *ShellConfig) Exec(ctx context.Context, opts *ExecOptions) error {
+	argv, stdin, err := s.argv(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+	cmd.Env = opts.Env
+	cmd.Dir = opts.Dir
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	err = cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("命令执行超时(%s)", opts.Timeout)
+	}
+	return err
+}
+
+// ExecuteCommand 命令配置, 支持字符串、参数数组与对象三种形式, 字符串与参数数组形式等同于仅配置run
+type ExecuteCommand struct {
+	// Name 步骤名称, 用于needs引用及日志输出
+	Name string `yaml:"name,omitempty"`
+	// Needs 依赖的步骤名称, 依赖的步骤全部执行成功后才会执行
+	Needs []string `yaml:"needs,omitempty"`
+	// Run 要执行的命令
+	Run string `yaml:"-"`
+	// Args 参数数组形式的命令, 通过 `run: [go, build, ./...]` 配置
+	Args []string `yaml:"-"`
+	// Dir 执行目录, 相对于工作目录, 不允许超出工作目录
+	Dir string `yaml:"dir,omitempty"`
+	// Env 额外的环境变量
+	Env *OrderFieldMap `yaml:"env,omitempty"`
+	// Timeout 超时时间, 例: 30s, 5m
+	Timeout time.Duration `yaml:"-"`
+	// IgnoreError 忽略执行错误
+	IgnoreError bool `yaml:"ignoreError,omitempty"`
+	// Retries 失败后的重试次数
+	Retries int `yaml:"retries,omitempty"`
+	// Shell 当前命令使用的shell
+	Shell *ShellConfig `yaml:"shell,omitempty"`
+	// Capture 将标准输出保存至的变量名称, 之后的模板与命令中可通过 `.this.Var` 或 `var` 获取
+	Capture string `yaml:"capture,omitempty"`
+	// CaptureParser 标准输出解析器: text(默认, 去除首尾空白) | json | yaml | raw(原样保存)
+	CaptureParser string `yaml:"captureParser,omitempty"`
+
+	line int
+}
+
+// parseCaptureOutput 按解析器解析命令的标准输出
+func parseCaptureOutput(parser string, output []byte) (interface{}, error) {
+	switch strings.ToLower(strings.TrimSpace(parser)) {
+	case "", "text":
+		return strings.TrimSpace(string(output)), nil
+	case "raw":
+		return string(output), nil
+	case "json":
+		var d interface{}
+		if err := json.Unmarshal(output, &d); err != nil {
+			return nil, fmt.Errorf("解析json输出失败: %w", err)
+		}
+		return d, nil
+	case "yaml":
+		var d interface{}
+		if err := yaml.Unmarshal(output, &d); err != nil {
+			return nil, fmt.Errorf("解析yaml输出失败: %w", err)
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("不支持的captureParser(输出解析器): %s", parser)
+	}
+}
+
+// decodeRun 解析字符串或参数数组形式的命令
+func (c *ExecuteCommand) decodeRun(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		c.Run = value.Value
+	case yaml.SequenceNode:
+		c.Args = make([]string, 0, len(value.Content))
+		for _, v := range value.Content {
+			if v.Kind != yaml.ScalarNode {
+				return fmt.Errorf("行: %d, 列: %d, 错误的命令参数类型", v.Line, v.Column)
+			}
+			c.Args = append(c.Args, v.Value)
+		}
+	default:
+		return fmt.Errorf("行: %d, 列: %d, 错误的run(要执行的命令)", value.Line, value.Column)
+	}
+	return nil
+}
+
+func (c *ExecuteCommand) UnmarshalYAML(value *yaml.Node) error {
+	c.line = value.Line
+	if value.Kind != yaml.MappingNode {
+		return c.decodeRun(value)
+	}
+
+	type executeCommand ExecuteCommand
+	r := &struct {
+		*executeCommand `yaml:",inline"`
+		Run             yaml.Node `yaml:"run"`
+		Timeout         string    `yaml:"timeout,omitempty"`
+	}{executeCommand: (*executeCommand)(c)}
+	if err := value.Decode(r); err != nil {
+		return err
+	}
+
+	if r.Run.Kind != 0 {
+		if err := c.decodeRun(&r.Run); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(c.Run) == "" && len(c.Args) == 0 {
+		return fmt.Errorf("行: %d, 列: %d, 缺失run(要执行的命令)", value.Line, value.Column)
+	}
+
+	if r.Timeout != "" {
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return fmt.Errorf("行: %d, 列: %d, 错误的timeout(超时时间): %s", value.Line, value.Column, r.Timeout)
+		}
+		c.Timeout = timeout
+	}
+
+	if c.Retries < 0 {
+		return fmt.Errorf("行: %d, 列: %d, retries(重试次数)不能小于0", value.Line, value.Column)
+	}
+	return nil
+}
+
+type ExecuteInfo struct {
+	Post []*ExecuteCommand `yaml:"post,omitempty"`
+	Pre  []*ExecuteCommand `yaml:"pre,omitempty"`
+	// AfterEnvs 环境变量(envs)解析完成后执行的命令
+	AfterEnvs []*ExecuteCommand `yaml:"afterEnvs,omitempty"`
+	// AfterVars 自定义变量(vars)解析完成后执行的命令
+	AfterVars []*ExecuteCommand `yaml:"afterVars,omitempty"`
+	// AfterRemoteVars 动态变量(remoteVars)解析完成后执行的命令
+	AfterRemoteVars []*ExecuteCommand `yaml:"afterRemoteVars,omitempty"`
+	// Concurrency 同时执行的最大步骤数, 大于1或命令配置了needs时按依赖关系并行执行, 默认为CPU核数
+	Concurrency int `yaml:"concurrency,omitempty"`
+	// FailurePolicy 并行执行时的失败策略: failFast(默认, 首个步骤失败后终止其余步骤) | collect(继续执行不依赖失败步骤的步骤并汇总错误)
+	FailurePolicy string `yaml:"failurePolicy,omitempty"`
+}
+
+func (e *ExecuteInfo) ExecPre(p *Parser, shell *ShellConfig, data map[string]interface{}, thisInfo *ThisInfo) error {
+	return p.execCommands(e.Pre, shell, data, thisInfo)
+}
+
+func (e *ExecuteInfo) ExecPost(p *Parser, shell *ShellConfig, data map[string]interface{}, thisInfo *ThisInfo) error {
+	return p.execCommands(e.Post, shell, data, thisInfo)
+}
+
+// execStageHook 执行阶段钩子命令, 执行完成后恢复当前阶段的this信息与日志块名称
+func (p *Parser) execStageHook(thisType ThisType, commands []*ExecuteCommand, data map[string]interface{}, thisInfo *ThisInfo) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	prevType, prevName, prevBlockName := thisInfo.Type, thisInfo.Name, p.logBlockName
+	defer func() {
+		thisInfo.Type, thisInfo.Name = prevType, prevName
+		p.SetLogBlockName(prevBlockName)
+	}()
+
+	thisInfo.Type = thisType
+	thisInfo.Name = string(thisType)
+	p.SetLogBlockName(string(thisType))
+	return p.execCommands(commands, p.shellConfig(), data, thisInfo)
+}
+
+// shellConfig 获取模板生效的shell配置
+func (p *Parser) shellConfig() *ShellConfig {
+	if p.TemplateInfo == nil || p.TemplateInfo.Shell.IsEmpty() {
+		return DefaultShellConfig
+	}
+	return &p.TemplateInfo.Shell
+}
+
+func (p *Parser) execCommands(commands []*ExecuteCommand, shell *ShellConfig, data map[string]interface{}, thisInfo *ThisInfo) error {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	var env []string
+	if p.TemplateInfo.Envs != nil && p.TemplateInfo.Envs.m != nil {
+		for _, k := range p.TemplateInfo.Envs.Keys() {
+			v, ok := p.TemplateInfo.Envs.Get(k)
+			if !ok {
+				continue
+			}
+			env = append(env, fmt.Sprintf("%s=%s", k, stringifyFieldValue(v)))
+		}
+	}
+
+	if p.parallelExecutes(commands) {
+		return p.execCommandGraph(commands, shell, env, data, thisInfo)
+	}
+
+	for i := range commands {
+		if err := p.execCommand(commands[i], shell, env, data, thisInfo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preparedCommand 渲染完成等待执行的命令
+type preparedCommand struct {
+	command     *ExecuteCommand
+	shell       *ShellConfig
+	opts        *ExecOptions
+	env         []string
+	commandLine string
+	blockName   string
+	stage       ThisType
+	name        string
+}
+
+// execCommand 执行单条命令, 按配置重试, ignoreError为true时仅记录错误.
+// env为模板中配置的环境变量, 执行时追加在当前进程的环境变量之后
+func (p *Parser) execCommand(command *ExecuteCommand, shell *ShellConfig, env []string, data map[string]interface{}, thisInfo *ThisInfo) error {
+	c, err := p.prepareCommand(command, shell, env, data, thisInfo)
+	if err != nil {
+		return err
+	}
+
+	captureBuf, err := p.runCommand(context.Background(), c, p.bufferWriter, p.execEventHandler)
+	return p.finishCommand(c, captureBuf, err)
+}
+
+// prepareCommand 渲染命令的run、args、dir与env并按执行策略校验
+func (p *Parser) prepareCommand(command *ExecuteCommand, shell *ShellConfig, env []string, data map[string]interface{}, thisInfo *ThisInfo) (c *preparedCommand, err error) {
+	opts := &ExecOptions{
+		Dir:     p.WorkerPath,
+		Timeout: command.Timeout,
+	}
+
+	if opts.Command, _, err = getStrByTemplate(command.Run, data, thisInfo); err != nil {
+		return nil, err
+	}
+
+	if len(command.Args) > 0 {
+		opts.Args = make([]string, len(command.Args))
+		for i := range command.Args {
+			if opts.Args[i], _, err = getStrByTemplate(command.Args[i], data, thisInfo); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if command.Dir != "" {
+		commandDir, _, err := getStrByTemplate(command.Dir, data, thisInfo)
+		if err != nil {
+			return nil, err
+		}
+		opts.Dir = filepath.Join(p.WorkerPath, commandDir)
+		if rel, err := filepath.Rel(p.WorkerPath, opts.Dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("行: %d, 执行目录[%s]不在工作目录内", command.line, commandDir)
+		}
+	}
+
+	if command.Env != nil && command.Env.m != nil {
+		commandEnv, err := renderCommandEnv(command.Env, data, thisInfo)
+		if err != nil {
+			return nil, err
+		}
+		env = append(append(make([]string, 0, len(env)+len(commandEnv)), env...), commandEnv...)
+	}
+	opts.Env = append(os.Environ(), env...)
+
+	if !command.Shell.IsEmpty() {
+		shell = command.Shell
+	}
+
+	if err = p.checkExecutePolicy(shell, opts); err != nil {
+		if command.line > 0 {
+			return nil, fmt.Errorf("行: %d, %w", command.line, err)
+		}
+		return nil, err
+	}
+
+	commandLine, err := shell.commandLine(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c = &preparedCommand{
+		command:     command,
+		shell:       shell,
+		opts:        opts,
+		env:         env,
+		commandLine: commandLine,
+		blockName:   p.logBlockName,
+		stage:       thisInfo.Type,
+		name:        thisInfo.Name,
+	}
+	if command.Name != "" {
+		c.blockName += "/" + command.Name
+	}
+	return c, nil
+}
+
+// renderCommandEnv 渲染命令的额外环境变量, 列表与Map与模板envs一致以JSON格式设置.
+// 不输出日志、不修改命令配置, 可在并行步骤中调用
+func renderCommandEnv(fieldMap *OrderFieldMap, data map[string]interface{}, thisInfo *ThisInfo) ([]string, error) {
+	keys := fieldMap.Keys()
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v, _ := fieldMap.Get(k)
+		rendered, err := renderFieldValue(v, data, thisInfo)
+		if err != nil {
+			return nil, fmt.Errorf("env[%s]: %w", k, err)
+		}
+		env = append(env, fmt.Sprintf("%s=%s", k, stringifyFieldValue(rendered)))
+	}
+	return env, nil
+}
+
+// runCommand 按重试次数执行命令, 日志与输出写入out, 返回需要保存至变量的标准输出
+func (p *Parser) runCommand(ctx context.Context, c *preparedCommand, out *bufio.Writer, handler ExecEventHandler) (captureBuf *bytes.Buffer, err error) {
+	var stdout io.Writer = out
+	if c.command.Capture != "" {
+		captureBuf = &bytes.Buffer{}
+		stdout = io.MultiWriter(out, captureBuf)
+	}
+
+	logEnv := c.env
+	if p.logFullExecuteEnv {
+		logEnv = c.opts.Env
+	}
+	envMarshal, _ := json.Marshal(logEnv)
+
+	for attempt := 0; attempt <= c.command.Retries; attempt++ {
+		if attempt > 0 {
+			p.logTo(out, c.blockName, "command failed: %s, retry %d/%d", err.Error(), attempt, c.command.Retries)
+		}
+		if captureBuf != nil {
+			captureBuf.Reset()
+		}
+
+		emitter := &execEventEmitter{
+			handler: handler,
+			base: ExecEvent{
+				Stage:   c.stage,
+				Name:    c.name,
+				Step:    c.command.Name,
+				Line:    c.command.line,
+				Command: c.commandLine,
+				Dir:     c.opts.Dir,
+				Attempt: attempt + 1,
+			},
+		}
+		stdoutWriter := emitter.writer(ExecEventStdout, stdout)
+		stderrWriter := emitter.writer(ExecEventStderr, out)
+		c.opts.Stdout, c.opts.Stderr = stdoutWriter, stderrWriter
+
+		p.logTo(out, c.blockName, "\ncommand => %s\ndir=>%s\nenv=>%s\noutput=>", c.commandLine, c.opts.Dir, envMarshal)
+		emitter.started()
+		err = c.shell.Exec(ctx, c.opts)
+		stdoutWriter.flush()
+		stderrWriter.flush()
+		emitter.exited(err)
+		_ = out.Flush()
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return captureBuf, err
+}
+
+// finishCommand 处理命令执行结果, 成功时保存输出至变量, 失败且ignoreError为true时仅记录错误
+func (p *Parser) finishCommand(c *preparedCommand, captureBuf *bytes.Buffer, err error) error {
+	if err == nil {
+		return p.captureOutput(c.command, captureBuf)
+	}
+
+	if c.command.IgnoreError {
+		p.logTo(p.bufferWriter, c.blockName, "command failed: %s, ignored", err.Error())
+		return nil
+	}
+
+	if c.command.line > 0 {
+		return fmt.Errorf("行: %d, 命令执行失败: %w", c.command.line, err)
+	}
+	return err
+}
+
+// captureOutput 将命令输出保存至变量
+func (p *Parser) captureOutput(command *ExecuteCommand, output *bytes.Buffer) error {
+	if command.Capture == "" {
+		return nil
+	}
+
+	val, err := parseCaptureOutput(command.CaptureParser, output.Bytes())
+	if err != nil {
+		return fmt.Errorf("行: %d, 保存命令输出至变量[%s]失败: %w", command.line, command.Capture, err)
+	}
+
+	if p.TemplateInfo.Vars == nil || p.TemplateInfo.Vars.m == nil {
+		p.TemplateInfo.Vars = NewOrderFieldMap()
+	}
+	p.TemplateInfo.Vars.Set(command.Capture, val)
+	p.LogWithPrevBlockName("${%s}: captured => %v", command.Capture, val)
+	return nil
+}
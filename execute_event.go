@@ -0,0 +1,169 @@
+package templateparser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ExecEventType 命令执行事件类型
+type ExecEventType string
+
+const (
+	// ExecEventStart 命令开始执行
+	ExecEventStart ExecEventType = "start"
+	// ExecEventStdout 标准输出的一行内容
+	ExecEventStdout ExecEventType = "stdout"
+	// ExecEventStderr 错误输出的一行内容
+	ExecEventStderr ExecEventType = "stderr"
+	// ExecEventExit 命令执行结束
+	ExecEventExit ExecEventType = "exit"
+)
+
+// ExecEvent 命令执行事件
+type ExecEvent struct {
+	// Type 事件类型
+	Type ExecEventType
+	// Stage 命令所在阶段, 例: executes-pre, executes-afterWrite
+	Stage ThisType
+	// Name 阶段名称, afterWrite阶段为模板key
+	Name string
+	// Step 步骤名称, 命令未配置name时为空
+	Step string
+	// Line 命令在模板中的行号
+	Line int
+	// Command 完整渲染后的命令
+	Command string
+	// Dir 执行目录
+	Dir string
+	// Attempt 当前执行次数, 从1开始, 重试时递增
+	Attempt int
+	// Time 事件时间
+	Time time.Time
+	// Output 输出内容, 仅stdout与stderr事件存在, 不包含换行符
+	Output string
+	// ExitCode 退出码, 仅exit事件存在, 命令未能启动或超时时为-1
+	ExitCode int
+	// Duration 执行耗时, 仅exit事件存在
+	Duration time.Duration
+	// Err 执行错误, 仅exit事件存在
+	Err error
+}
+
+// ExecEventHandler 命令执行事件处理函数
+type ExecEventHandler func(event *ExecEvent)
+
+// OnExecEvent 设置命令执行事件处理函数, 输出事件按行回调, 同一命令的事件按顺序串行回调
+func (p *Parser) OnExecEvent(handler ExecEventHandler) *Parser {
+	p.execEventHandler = handler
+	return p
+}
+
+// LogExecuteEnv 日志中是否输出命令执行时的完整环境变量, 默认仅输出模板中配置的环境变量
+func (p *Parser) LogExecuteEnv(full bool) *Parser {
+	p.logFullExecuteEnv = full
+	return p
+}
+
+// execEventEmitter 单次命令执行的事件发送器
+type execEventEmitter struct {
+	lock    sync.Mutex
+	handler ExecEventHandler
+	base    ExecEvent
+	start   time.Time
+}
+
+// emit 发送事件
+func (e *execEventEmitter) emit(event ExecEvent) {
+	if e.handler == nil {
+		return
+	}
+	event.Stage = e.base.Stage
+	event.Name = e.base.Name
+	event.Step = e.base.Step
+	event.Line = e.base.Line
+	event.Command = e.base.Command
+	event.Dir = e.base.Dir
+	event.Attempt = e.base.Attempt
+	event.Time = time.Now()
+	e.handler(&event)
+}
+
+// started 发送开始事件
+func (e *execEventEmitter) started() {
+	e.start = time.Now()
+	e.emit(ExecEvent{Type: ExecEventStart})
+}
+
+// exited 发送结束事件
+func (e *execEventEmitter) exited(err error) {
+	exitCode := 0
+	if err != nil {
+		exitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+	e.emit(ExecEvent{
+		Type:     ExecEventExit,
+		ExitCode: exitCode,
+		Duration: time.Since(e.start),
+		Err:      err,
+	})
+}
+
+// writer 获取按行发送输出事件的writer, 输出同时写入out
+func (e *execEventEmitter) writer(eventType ExecEventType, out io.Writer) *execLineWriter {
+	return &execLineWriter{
+		emitter:   e,
+		eventType: eventType,
+		out:       out,
+	}
+}
+
+// execLineWriter 按行发送输出事件的writer
+type execLineWriter struct {
+	emitter   *execEventEmitter
+	eventType ExecEventType
+	out       io.Writer
+	buf       []byte
+}
+
+func (w *execLineWriter) Write(b []byte) (int, error) {
+	w.emitter.lock.Lock()
+	defer w.emitter.lock.Unlock()
+
+	if _, err := w.out.Write(b); err != nil {
+		return 0, err
+	}
+
+	if w.emitter.handler == nil {
+		return len(b), nil
+	}
+
+	w.buf = append(w.buf, b...)
+	for {
+		index := bytes.IndexByte(w.buf, '\n')
+		if index == -1 {
+			break
+		}
+		w.emitter.emit(ExecEvent{Type: w.eventType, Output: string(bytes.TrimSuffix(w.buf[:index], []byte{'\r'}))})
+		w.buf = w.buf[index+1:]
+	}
+	return len(b), nil
+}
+
+// flush 发送剩余不足一行的输出
+func (w *execLineWriter) flush() {
+	w.emitter.lock.Lock()
+	defer w.emitter.lock.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emitter.emit(ExecEvent{Type: w.eventType, Output: string(w.buf)})
+		w.buf = nil
+	}
+}
@@ -0,0 +1,237 @@
+package templateparser
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	// ExecuteFailFast 首个步骤失败后终止其余步骤
+	ExecuteFailFast = "failFast"
+	// ExecuteCollectFailures 继续执行不依赖失败步骤的步骤, 结束后汇总错误
+	ExecuteCollectFailures = "collect"
+)
+
+// executeStep 并行执行中的步骤
+type executeStep struct {
+	name    string
+	command *ExecuteCommand
+	// needs 未完成的依赖数量
+	needs int
+	// dependents 依赖当前步骤的步骤
+	dependents []*executeStep
+}
+
+// executeStepResult 步骤执行结果
+type executeStepResult struct {
+	step *executeStep
+	err  error
+}
+
+// executeOptions 获取命令的全局执行配置
+func (p *Parser) executeOptions() *ExecuteInfo {
+	if p.TemplateInfo == nil || p.TemplateInfo.Executes == nil {
+		return &ExecuteInfo{}
+	}
+	return p.TemplateInfo.Executes
+}
+
+// parallelExecutes 判断命令是否需要按依赖关系并行执行
+func (p *Parser) parallelExecutes(commands []*ExecuteCommand) bool {
+	if len(commands) < 2 {
+		return false
+	}
+
+	if p.executeOptions().Concurrency > 1 {
+		return true
+	}
+
+	for _, command := range commands {
+		if len(command.Needs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildExecuteSteps 构建步骤依赖关系, 校验步骤名称与依赖并检测循环依赖
+func buildExecuteSteps(commands []*ExecuteCommand) ([]*executeStep, error) {
+	steps := make([]*executeStep, len(commands))
+	stepMap := make(map[string]*executeStep, len(commands))
+	for i, command := range commands {
+		step := &executeStep{
+			name:    command.Name,
+			command: command,
+		}
+		if step.name == "" {
+			step.name = fmt.Sprintf("#%d", i+1)
+		} else if _, ok := stepMap[step.name]; ok {
+			return nil, fmt.Errorf("行: %d, 步骤名称[%s]重复", command.line, step.name)
+		}
+		stepMap[step.name] = step
+		steps[i] = step
+	}
+
+	for _, step := range steps {
+		for _, need := range step.command.Needs {
+			dependency, ok := stepMap[need]
+			if !ok || dependency.command.Name == "" {
+				return nil, fmt.Errorf("行: %d, 步骤[%s]依赖的步骤[%s]不存在", step.command.line, step.name, need)
+			}
+			step.needs++
+			dependency.dependents = append(dependency.dependents, step)
+		}
+	}
+
+	needs := make(map[*executeStep]int, len(steps))
+	queue := make([]*executeStep, 0, len(steps))
+	for _, step := range steps {
+		needs[step] = step.needs
+		if step.needs == 0 {
+			queue = append(queue, step)
+		}
+	}
+	for i := 0; i < len(queue); i++ {
+		for _, dependent := range queue[i].dependents {
+			if needs[dependent]--; needs[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	if len(queue) != len(steps) {
+		cycle := make([]string, 0, len(steps)-len(queue))
+		for _, step := range steps {
+			if needs[step] > 0 {
+				cycle = append(cycle, step.name)
+			}
+		}
+		return nil, fmt.Errorf("步骤存在循环依赖: %s", strings.Join(cycle, ", "))
+	}
+
+	return steps, nil
+}
+
+// execCommandGraph 按依赖关系并行执行命令, 每个步骤的输出单独缓存, 步骤结束后整体写入日志
+func (p *Parser) execCommandGraph(commands []*ExecuteCommand, shell *ShellConfig, env []string, data map[string]interface{}, thisInfo *ThisInfo) error {
+	options := p.executeOptions()
+	failFast := true
+	switch options.FailurePolicy {
+	case "", ExecuteFailFast:
+	case ExecuteCollectFailures:
+		failFast = false
+	default:
+		return fmt.Errorf("不支持的失败策略[%s], 可选: %s | %s", options.FailurePolicy, ExecuteFailFast, ExecuteCollectFailures)
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+
+	steps, err := buildExecuteSteps(commands)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// lock 保护模板渲染、变量保存、日志与事件回调
+	var lock sync.Mutex
+	handler := p.execEventHandler
+	if handler != nil {
+		handler = func(event *ExecEvent) {
+			lock.Lock()
+			defer lock.Unlock()
+			p.execEventHandler(event)
+		}
+	}
+
+	results := make(chan *executeStepResult)
+	run := func(step *executeStep) {
+		lock.Lock()
+		c, err := p.prepareCommand(step.command, shell, env, data, thisInfo)
+		lock.Unlock()
+
+		var captureBuf *bytes.Buffer
+		output := &bytes.Buffer{}
+		if err == nil {
+			captureBuf, err = p.runCommand(ctx, c, bufio.NewWriter(output), handler)
+		}
+
+		lock.Lock()
+		_, _ = p.bufferWriter.Write(output.Bytes())
+		_ = p.bufferWriter.Flush()
+		if c != nil {
+			err = p.finishCommand(c, captureBuf, err)
+		}
+		lock.Unlock()
+
+		results <- &executeStepResult{step: step, err: err}
+	}
+
+	ready := make([]*executeStep, 0, len(steps))
+	for _, step := range steps {
+		if step.needs == 0 {
+			ready = append(ready, step)
+		}
+	}
+
+	var failures []string
+	running, finished := 0, make(map[*executeStep]bool, len(steps))
+	for {
+		for len(ready) > 0 && running < concurrency && (!failFast || len(failures) == 0) {
+			running++
+			go run(ready[0])
+			ready = ready[1:]
+		}
+		if running == 0 {
+			break
+		}
+
+		result := <-results
+		running--
+		finished[result.step] = true
+		if result.err != nil {
+			if failFast && ctx.Err() != nil {
+				failures = append(failures, fmt.Sprintf("[%s]: 已终止", result.step.name))
+				continue
+			}
+			failures = append(failures, fmt.Sprintf("[%s]: %s", result.step.name, result.err.Error()))
+			if failFast {
+				cancel()
+			}
+			continue
+		}
+
+		for _, dependent := range result.step.dependents {
+			if dependent.needs--; dependent.needs == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	skipped := make([]string, 0)
+	for _, step := range steps {
+		if !finished[step] {
+			skipped = append(skipped, step.name)
+		}
+	}
+
+	msg := "步骤执行失败: " + strings.Join(failures, "; ")
+	if len(skipped) > 0 {
+		msg += ", 未执行的步骤: " + strings.Join(skipped, ", ")
+	}
+	p.LogWithPrevBlockName("%s", msg)
+	return errors.New(msg)
+}
@@ -0,0 +1,378 @@
+package templateparser
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// shellMetaChars shell元字符
+const shellMetaChars = "|&;<>()$`\\\n\r*?[]{}!~"
+
+var (
+	// shellOptionPattern 允许的shell解释器选项, 例: -c、-eu、--noprofile、/c
+	shellOptionPattern = regexp.MustCompile(`^([-+][A-Za-z][A-Za-z-]*|/[A-Za-z])$`)
+	// shellOptionValuePattern 允许的 -o、-O 选项值, 例: pipefail
+	shellOptionValuePattern = regexp.MustCompile(`^[A-Za-z_]+$`)
+)
+
+// ExecConfirmInfo 命令执行确认信息
+type ExecConfirmInfo struct {
+	// Command 完整渲染后的命令行, 包含shell解释器
+	Command string
+	// Argv 实际执行的参数数组, shell模式下仅有解释器时命令通过标准输入传递
+	Argv []string
+	// Stdin 通过标准输入传递的命令
+	Stdin string
+	// Executables 命令中解析到的可执行文件
+	Executables []string
+	// Dir 执行目录
+	Dir string
+}
+
+// ExecutePolicy 命令(executes)执行策略
+type ExecutePolicy struct {
+	// Disabled 禁止执行任何命令
+	Disabled bool
+	// Allow 允许执行的可执行文件, 支持名称或绝对路径, 为空时不限制
+	Allow []string
+	// Deny 禁止执行的可执行文件, 支持名称或绝对路径, 优先级高于Allow.
+	// 仅能识别shell关键字及env、xargs等常见包装命令后的可执行文件, 无法识别解释器内部执行的命令(例: sh -c、find -exec),
+	// 因此Deny不是安全边界, 执行不受信任的模板时应使用Allow
+	Deny []string
+	// NoShellMetaChars 禁止shell模式的命令中包含shell元字符(管道、重定向、命令替换、通配符等)
+	NoShellMetaChars bool
+	// Confirm 执行前的确认回调, 返回false时拒绝执行该命令
+	Confirm func(info *ExecConfirmInfo) (bool, error)
+}
+
+// SetExecutePolicy 设置命令(executes)执行策略, 对模板及其导入模板中的所有命令生效
+func (p *Parser) SetExecutePolicy(policy *ExecutePolicy) *Parser {
+	p.executePolicy = policy
+	return p
+}
+
+// DisableExecutes 禁止执行模板中的任何命令
+func (p *Parser) DisableExecutes() *Parser {
+	if p.executePolicy == nil {
+		p.executePolicy = &ExecutePolicy{}
+	}
+	p.executePolicy.Disabled = true
+	return p
+}
+
+// checkExecutePolicy 按执行策略校验即将执行的命令
+func (p *Parser) checkExecutePolicy(shell *ShellConfig, opts *ExecOptions) error {
+	policy := p.executePolicy
+	if policy == nil {
+		return nil
+	}
+
+	argv, stdin, err := shell.argv(opts)
+	if err != nil {
+		return err
+	}
+
+	command, err := shell.commandLine(opts)
+	if err != nil {
+		return err
+	}
+
+	if policy.Disabled {
+		return fmt.Errorf("命令执行已被禁用, 拒绝执行: %s", command)
+	}
+
+	if !shell.exec {
+		if err = policy.checkShell(shell, command); err != nil {
+			return err
+		}
+	}
+
+	strict := len(policy.Allow) > 0 || len(policy.Deny) > 0
+	var executables []string
+	if shell.exec || len(opts.Args) > 0 {
+		words := opts.Args
+		if shell.exec {
+			words = argv
+		}
+		if executables, err = commandExecutables(words, strict); err != nil {
+			return fmt.Errorf("%s, 拒绝执行: %s", err.Error(), command)
+		}
+	} else {
+		if policy.NoShellMetaChars {
+			if i := strings.IndexAny(opts.Command, shellMetaChars); i != -1 {
+				return fmt.Errorf("命令中包含shell元字符[%q], 拒绝执行: %s", opts.Command[i], command)
+			}
+		}
+
+		if executables, err = shellCommandExecutables(opts.Command, strict); err != nil {
+			return fmt.Errorf("%s, 拒绝执行: %s", err.Error(), command)
+		}
+	}
+
+	for _, executable := range executables {
+		if err = policy.checkExecutable(executable, command); err != nil {
+			return err
+		}
+	}
+
+	if policy.Confirm == nil {
+		return nil
+	}
+
+	ok, err := policy.Confirm(&ExecConfirmInfo{
+		Command:     command,
+		Argv:        argv,
+		Stdin:       stdin,
+		Executables: executables,
+		Dir:         opts.Dir,
+	})
+	if err != nil {
+		return fmt.Errorf("命令执行确认失败: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("命令未被确认, 拒绝执行: %s", command)
+	}
+	return nil
+}
+
+// checkExecutable 按允许与禁止列表校验可执行文件
+func (e *ExecutePolicy) checkExecutable(executable, command string) error {
+	if matchExecutable(e.Deny, executable) {
+		return fmt.Errorf("可执行文件[%s]已被禁止, 拒绝执行: %s", executable, command)
+	}
+	if len(e.Allow) > 0 && !matchExecutable(e.Allow, executable) {
+		return fmt.Errorf("可执行文件[%s]不在允许列表中, 拒绝执行: %s", executable, command)
+	}
+	return nil
+}
+
+// checkShell 校验shell解释器及其参数, 解释器按允许与禁止列表校验(默认解释器无需配置在允许列表中),
+// 解释器参数仅允许为选项, 防止通过参数执行未经校验的命令, 例: [sh, -c, "touch x", x]
+func (e *ExecutePolicy) checkShell(shell *ShellConfig, command string) error {
+	args := shell.args
+	if len(args) == 0 {
+		args = DefaultShellConfig.args
+	}
+
+	interpreter := args[0]
+	if matchExecutable(e.Deny, interpreter) {
+		return fmt.Errorf("shell解释器[%s]已被禁止, 拒绝执行: %s", interpreter, command)
+	}
+	if len(e.Allow) > 0 && interpreter != DefaultShellConfig.args[0] && !matchExecutable(e.Allow, interpreter) {
+		return fmt.Errorf("shell解释器[%s]不在允许列表中, 拒绝执行: %s", interpreter, command)
+	}
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if !shellOptionPattern.MatchString(arg) {
+			return fmt.Errorf("shell参数[%s]不是解释器选项, 拒绝执行: %s", arg, command)
+		}
+		switch arg {
+		case "-o", "+o", "-O", "+O":
+			if i+1 < len(args) && shellOptionValuePattern.MatchString(args[i+1]) {
+				i++
+			}
+		}
+	}
+	return nil
+}
+
+// matchExecutable 判断可执行文件是否在列表中, 列表项为名称时匹配可执行文件的文件名
+func matchExecutable(list []string, executable string) bool {
+	for _, item := range list {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if item == executable {
+			return true
+		}
+		if !strings.ContainsAny(item, `/\`) && item == filepath.Base(executable) {
+			return true
+		}
+	}
+	return false
+}
+
+// shellCommandExecutables 按管道、命令列表等分隔符拆分shell命令并获取每段命令的可执行文件,
+// strict为true时命令中存在无法静态解析的命令替换将返回错误
+func shellCommandExecutables(command string, strict bool) ([]string, error) {
+	var (
+		executables []string
+		segment     strings.Builder
+		quote       rune
+		escaped     bool
+	)
+
+	flush := func() error {
+		words, err := splitCommandLine(segment.String())
+		segment.Reset()
+		if err != nil {
+			return err
+		}
+		res, err := commandExecutables(words, strict)
+		if err != nil {
+			return err
+		}
+		executables = append(executables, res...)
+		return nil
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if strict && quote == '"' && (c == '`' || c == '$' && i+1 < len(runes) && runes[i+1] == '(') {
+				return nil, errors.New("命令中包含无法校验的命令替换")
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strict && (c == '`' || c == '$' && i+1 < len(runes) && runes[i+1] == '('):
+			return nil, errors.New("命令中包含无法校验的命令替换")
+		case c == '|' || c == '&' || c == ';' || c == '\n' || c == '(' || c == ')':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		segment.WriteRune(c)
+	}
+
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return executables, nil
+}
+
+// commandWrapper 执行其参数中命令的包装命令或shell关键字
+type commandWrapper struct {
+	// keyword shell关键字, 不作为可执行文件校验
+	keyword bool
+	// valueOptions 需要参数值的选项
+	valueOptions []string
+	// positional 实际命令前的位置参数数量, 例: timeout 10 go build
+	positional int
+	// assignments 实际命令前允许设置环境变量, 例: env FOO=bar go build
+	assignments bool
+}
+
+var (
+	// commandWrappers 包装命令及shell关键字, 校验时跳过其选项并继续校验实际执行的命令
+	commandWrappers = map[string]*commandWrapper{
+		"if":      {keyword: true},
+		"then":    {keyword: true},
+		"elif":    {keyword: true},
+		"else":    {keyword: true},
+		"fi":      {keyword: true},
+		"while":   {keyword: true},
+		"until":   {keyword: true},
+		"do":      {keyword: true},
+		"done":    {keyword: true},
+		"esac":    {keyword: true},
+		"{":       {keyword: true},
+		"}":       {keyword: true},
+		"!":       {keyword: true},
+		"coproc":  {keyword: true},
+		"time":    {keyword: true},
+		"env":     {valueOptions: []string{"-u", "--unset", "-C", "--chdir"}, assignments: true},
+		"command": {},
+		"builtin": {},
+		"exec":    {valueOptions: []string{"-a"}},
+		"nohup":   {},
+		"setsid":  {},
+		"nice":    {valueOptions: []string{"-n", "--adjustment"}},
+		"timeout": {valueOptions: []string{"-s", "--signal", "-k", "--kill-after"}, positional: 1},
+		"stdbuf":  {valueOptions: []string{"-i", "-o", "-e"}},
+		"xargs":   {valueOptions: []string{"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s"}},
+		"sudo":    {valueOptions: []string{"-u", "-g", "-C", "-D", "-h", "-p", "-r", "-t", "-U"}},
+	}
+
+	// uncheckableKeywords 无法静态解析实际执行命令的shell关键字及内建命令
+	uncheckableKeywords = []string{"for", "select", "case", "function", "eval", "trap"}
+
+	// redirectPattern 命令前的重定向, 例: 2>/dev/null、> out.txt
+	redirectPattern = regexp.MustCompile(`^[0-9]*(<|>|>>|<<|<<<|<>|>\||&>|&>>|>&|<&)(.*)$`)
+)
+
+// commandExecutables 获取一段命令中实际执行的可执行文件, 跳过环境变量赋值、重定向、shell关键字及包装命令的选项,
+// 包装命令本身同样作为可执行文件返回. strict为true时无法静态解析的命令将返回错误
+func commandExecutables(words []string, strict bool) ([]string, error) {
+	var executables []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		// 跳过命令前的环境变量赋值, 例: FOO=bar go build
+		if j := strings.Index(word, "="); j > 0 && !strings.ContainsAny(word[:j], `/\`) {
+			continue
+		}
+		if m := redirectPattern.FindStringSubmatch(word); m != nil {
+			if m[2] == "" {
+				i++
+			}
+			continue
+		}
+
+		if strict {
+			for _, keyword := range uncheckableKeywords {
+				if word == keyword {
+					return nil, fmt.Errorf("命令中包含无法校验的shell语法[%s]", word)
+				}
+			}
+			if word != "[" && word != "[[" && strings.ContainsAny(word, "$*?[") {
+				return nil, fmt.Errorf("命令中包含无法校验的可执行文件[%s]", word)
+			}
+		}
+
+		wrapper, ok := commandWrappers[word]
+		if !ok {
+			return append(executables, word), nil
+		}
+		if !wrapper.keyword {
+			executables = append(executables, word)
+		}
+
+		// 跳过包装命令的选项及位置参数
+		positional := wrapper.positional
+		for i+1 < len(words) {
+			next := words[i+1]
+			if next == "--" {
+				i++
+				break
+			}
+			if strings.HasPrefix(next, "-") && len(next) > 1 && !wrapper.keyword || word == "time" && next == "-p" {
+				if strict && word == "env" && (strings.HasPrefix(next, "-S") || strings.HasPrefix(next, "--split-string")) {
+					return nil, fmt.Errorf("命令中包含无法校验的选项[%s %s]", word, next)
+				}
+				i++
+				for _, option := range wrapper.valueOptions {
+					if next == option {
+						i++
+						break
+					}
+				}
+				continue
+			}
+			if wrapper.assignments && strings.Index(next, "=") > 0 {
+				i++
+				continue
+			}
+			if positional > 0 {
+				positional--
+				i++
+				continue
+			}
+			break
+		}
+	}
+	return executables, nil
+}
@@ -0,0 +1,560 @@
+package templateparser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommands(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+envs:
+  GREETING: hello
+executes:
+  pre:
+    - mkdir -p sub
+    - run: echo "$GREETING $NAME" > greeting.txt
+      dir: sub
+      env:
+        NAME: '{{ .this.ModuleName }}'
+    - run: exit 1
+      ignoreError: true
+    - run: test -f retried || (touch retried && exit 1)
+      retries: 1
+`), &ProjectInfo{Name: "demo"})) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "sub", "greeting.txt"))
+	if a.NoError(err) {
+		a.Equal("hello demo\n", string(content))
+	}
+
+	err = parser.Decode([]byte(`
+executes:
+  pre:
+    - run: sleep 5
+      timeout: 100ms
+`), nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "超时")
+	}
+
+	err = parser.Decode([]byte(`
+vars:
+  dir: ../..
+executes:
+  pre:
+    - run: touch escaped.txt
+      dir: 'sub/{{ .this.Var "dir" }}'
+`), nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "行: 6, 执行目录[sub/../..]不在工作目录内")
+		a.NoFileExists(filepath.Join(filepath.Dir(parser.WorkerPath), "escaped.txt"))
+	}
+
+	if a.NoError(parser.Decode([]byte(`
+executes:
+  pre:
+    - run: echo "$MODULES" > modules.txt
+      env:
+        MODULES: [api, web]
+`), nil)) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "modules.txt"))
+		if a.NoError(err) {
+			a.Equal(`["api","web"]`+"\n", string(content))
+		}
+	}
+}
+
+func TestShellConfig(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+shell: [bash, -eu, -o, pipefail, -c]
+executes:
+  pre:
+    - echo bash | cat > bash.txt
+    - run: echo sh > sh.txt
+      shell: sh
+    - run: [touch, "file with space.txt"]
+      shell: exec
+    - run: mkdir "dir with space"
+      shell: exec
+    - run: [touch, "dir with space/quoted.txt"]
+`), nil)) {
+		return
+	}
+
+	for _, name := range []string{"bash.txt", "sh.txt", "file with space.txt", "dir with space/quoted.txt"} {
+		a.FileExists(filepath.Join(parser.WorkerPath, name))
+	}
+	a.NoDirExists(filepath.Join(parser.WorkerPath, `"dir`))
+
+	args, err := splitCommandLine(`bash -c 'echo "a b"' "c\"d"`)
+	if a.NoError(err) {
+		a.Equal([]string{"bash", "-c", `echo "a b"`, `c"d`}, args)
+	}
+}
+
+func TestExecuteCapture(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+executes:
+  pre:
+    - run: echo "  v1.2.3  "
+      capture: version
+    - run: echo '{"modules":["api","web"]}'
+      capture: project
+      captureParser: json
+  post:
+    - echo '{{ .this.Var "version" }}' > post.txt
+templates:
+  "{{ .v0 }}.txt":
+    content: '{{ .this | var "version" }}'
+    range: (.this.Var "project").modules
+`), nil)) {
+		return
+	}
+
+	for _, name := range []string{"api.txt", "web.txt", "post.txt"} {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, name))
+		if a.NoError(err) {
+			a.Equal("v1.2.3", strings.TrimSpace(string(content)))
+		}
+	}
+}
+
+func TestExecuteHooks(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+envs:
+  HOOK_NAME: demo
+vars:
+  greeting: hello
+executes:
+  afterEnvs:
+    - echo "$HOOK_NAME" > envs.txt
+  afterVars:
+    - echo '{{ .this.Var "greeting" }}' > vars.txt
+templates:
+  bin/run.sh:
+    content: "#!/bin/sh"
+    afterWrite:
+      - chmod +x {{ .path }}
+      - echo '{{ .key }}:{{ .path }}' > hook.txt
+  "{{ .v0 }}.txt":
+    content: '{{ .v0 }}'
+    range: list "a" "b"
+    afterWrite:
+      - echo '{{ .path }}' >> ranges.txt
+`), nil)) {
+		return
+	}
+
+	stat, err := os.Stat(filepath.Join(parser.WorkerPath, "bin", "run.sh"))
+	if a.NoError(err) {
+		a.NotZero(stat.Mode().Perm() & 0100)
+	}
+
+	expected := map[string]string{
+		"envs.txt":   "demo",
+		"vars.txt":   "hello",
+		"hook.txt":   "bin/run.sh:" + filepath.Join("bin", "run.sh"),
+		"ranges.txt": "a.txt\nb.txt",
+	}
+	for name, value := range expected {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, name))
+		if a.NoError(err) {
+			a.Equal(value, strings.TrimSpace(string(content)))
+		}
+	}
+}
+
+func TestExecutePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	decode := func(policy *ExecutePolicy, command string) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.SetExecutePolicy(policy).Decode([]byte(`
+executes:
+  pre:
+    - `+command+`
+`), nil)
+	}
+
+	_, err := decode(&ExecutePolicy{Disabled: true}, "echo hi")
+	if a.Error(err) {
+		a.Contains(err.Error(), "命令执行已被禁用")
+	}
+
+	_, err = decode(&ExecutePolicy{Allow: []string{"echo"}}, "echo hi | rm -rf x")
+	if a.Error(err) {
+		a.Contains(err.Error(), "可执行文件[rm]不在允许列表中")
+	}
+
+	_, err = decode(&ExecutePolicy{Allow: []string{"echo"}}, "echo $(rm -rf x)")
+	if a.Error(err) {
+		a.Contains(err.Error(), "命令替换")
+	}
+
+	_, err = decode(&ExecutePolicy{Deny: []string{"rm"}}, "FOO=1 /bin/rm -rf x")
+	if a.Error(err) {
+		a.Contains(err.Error(), "可执行文件[/bin/rm]已被禁止")
+	}
+
+	for _, command := range []string{
+		"env FOO=1 touch pwned",
+		"if true; then touch pwned; fi",
+		"'{ touch pwned; }'",
+		"! nohup touch pwned",
+		"timeout -s KILL 10 xargs -n 1 touch <<< pwned",
+		"2>/dev/null command touch pwned",
+		`run: [env, -u, HOME, touch, pwned]
+      shell: exec`,
+	} {
+		parser, err := decode(&ExecutePolicy{Deny: []string{"touch"}}, command)
+		if a.Error(err, command) {
+			a.Contains(err.Error(), "可执行文件[touch]已被禁止", command)
+			a.NoFileExists(filepath.Join(parser.WorkerPath, "pwned"), command)
+		}
+	}
+
+	for _, command := range []string{"for f in a; do echo $f; done", "eval touch pwned", "T=touch; $T pwned", "env -S 'touch pwned'"} {
+		_, err = decode(&ExecutePolicy{Deny: []string{"touch"}}, command)
+		if a.Error(err, command) {
+			a.Contains(err.Error(), "无法校验", command)
+		}
+	}
+
+	parser, err := decode(&ExecutePolicy{Allow: []string{"touch", "["}}, "if [ ! -f ok.txt ]; then touch ok.txt; fi")
+	if a.NoError(err) {
+		a.FileExists(filepath.Join(parser.WorkerPath, "ok.txt"))
+	}
+
+	_, err = decode(&ExecutePolicy{NoShellMetaChars: true}, "echo hi > out.txt")
+	if a.Error(err) {
+		a.Contains(err.Error(), "shell元字符")
+	}
+
+	parser, err = decode(&ExecutePolicy{Allow: []string{"git"}, NoShellMetaChars: true}, `run: git
+      shell: [sh, -c, "touch pwned", x]`)
+	if a.Error(err) {
+		a.Contains(err.Error(), "shell解释器[sh]不在允许列表中")
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "pwned"))
+	}
+
+	parser, err = decode(&ExecutePolicy{Allow: []string{"git", "sh"}}, `run: git
+      shell: [sh, -c, "touch pwned", x]`)
+	if a.Error(err) {
+		a.Contains(err.Error(), "shell参数[touch pwned]不是解释器选项")
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "pwned"))
+	}
+
+	parser, err = decode(&ExecutePolicy{Deny: []string{"bash"}}, "echo hi")
+	if a.Error(err) {
+		a.Contains(err.Error(), "shell解释器[bash]已被禁止")
+	}
+
+	parser, err = decode(&ExecutePolicy{Allow: []string{"touch"}}, `run: touch options.txt
+      shell: [bash, -eu, -o, pipefail, -c]`)
+	if a.NoError(err) {
+		a.FileExists(filepath.Join(parser.WorkerPath, "options.txt"))
+	}
+
+	var confirmed []string
+	parser, err = decode(&ExecutePolicy{
+		Allow: []string{"echo", "touch"},
+		Confirm: func(info *ExecConfirmInfo) (bool, error) {
+			confirmed = append(confirmed, info.Command)
+			return true, nil
+		},
+	}, `run: [touch, '{{ print "ok" }}.txt']`)
+	if a.NoError(err) {
+		a.Equal([]string{"bash -c 'touch ok.txt'"}, confirmed)
+		a.FileExists(filepath.Join(parser.WorkerPath, "ok.txt"))
+	}
+
+	_, err = decode(&ExecutePolicy{
+		Confirm: func(info *ExecConfirmInfo) (bool, error) {
+			return false, nil
+		},
+	}, "echo hi")
+	if a.Error(err) {
+		a.Contains(err.Error(), "命令未被确认")
+	}
+}
+
+func TestExecuteEvents(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	var events []*ExecEvent
+	if !a.NoError(parser.OnExecEvent(func(event *ExecEvent) {
+		events = append(events, event)
+	}).Decode([]byte(`
+executes:
+  pre:
+    - echo out1; echo err1 >&2; printf out2
+    - run: exit 3
+      ignoreError: true
+`), nil)) {
+		return
+	}
+
+	var outputs []string
+	for _, event := range events {
+		a.Equal(ThisTypeExecutePre, event.Stage)
+		if event.Type == ExecEventStdout || event.Type == ExecEventStderr {
+			outputs = append(outputs, string(event.Type)+":"+event.Output)
+		}
+	}
+	a.ElementsMatch([]string{"stdout:out1", "stderr:err1", "stdout:out2"}, outputs)
+
+	if a.Len(events, 7) {
+		a.Equal(ExecEventStart, events[0].Type)
+		a.Equal(ExecEventExit, events[4].Type)
+		a.Equal(0, events[4].ExitCode)
+		a.Equal(ExecEventStart, events[5].Type)
+		a.Equal("bash -c 'exit 3'", events[5].Command)
+		a.Equal(ExecEventExit, events[6].Type)
+		a.Equal(3, events[6].ExitCode)
+		a.Error(events[6].Err)
+	}
+}
+
+func TestExecuteGraph(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	decode := func(executes string) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.Decode([]byte("executes:\n"+executes), nil)
+	}
+
+	start := time.Now()
+	parser, err := decode(`
+  concurrency: 2
+  post:
+    - name: a
+      run: sleep 0.3; echo a > a.txt
+    - name: b
+      run: sleep 0.3; echo b | tee b.txt
+      capture: b
+    - name: c
+      needs: [a, b]
+      run: cat a.txt b.txt > c.txt; echo '{{ .this.Var "b" }}' >> c.txt
+`)
+	if a.NoError(err) {
+		a.Less(time.Since(start), 550*time.Millisecond)
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "c.txt"))
+		if a.NoError(err) {
+			a.Equal("a\nb\nb\n", string(content))
+		}
+	}
+
+	_, err = decode(`
+  post:
+    - name: a
+      needs: [b]
+      run: "true"
+    - name: b
+      needs: [a]
+      run: "true"
+`)
+	if a.Error(err) {
+		a.Contains(err.Error(), "循环依赖")
+	}
+
+	parser, err = decode(`
+  failurePolicy: collect
+  post:
+    - name: fail
+      run: exit 1
+    - name: other
+      run: touch other.txt
+      needs: []
+    - name: after
+      needs: [fail]
+      run: touch after.txt
+`)
+	if a.Error(err) {
+		a.Contains(err.Error(), "[fail]")
+		a.Contains(err.Error(), "未执行的步骤: after")
+		a.FileExists(filepath.Join(parser.WorkerPath, "other.txt"))
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "after.txt"))
+	}
+
+	// 并行步骤的env渲染不应修改共享的日志状态及命令配置, 需配合 go test -race 检查
+	parser, err = decode(`
+  concurrency: 4
+  post:
+    - name: a
+      env: {NAME: a, LIST: [x, "{{ \"y\" }}"]}
+      run: echo "$NAME $LIST" > a.txt
+    - name: b
+      env: {NAME: b}
+      run: echo "$NAME" > b.txt
+    - name: c
+      env: {NAME: c}
+      run: echo "$NAME" > c.txt
+    - name: d
+      env: {NAME: d}
+      run: echo "$NAME" > d.txt
+`)
+	if a.NoError(err) {
+		for _, name := range []string{"b", "c", "d"} {
+			content, err := os.ReadFile(filepath.Join(parser.WorkerPath, name+".txt"))
+			if a.NoError(err) {
+				a.Equal(name+"\n", string(content))
+			}
+		}
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "a.txt"))
+		if a.NoError(err) {
+			a.Equal("a [\"x\",\"y\"]\n", string(content))
+		}
+	}
+}
+
+func TestExecuteRequires(t *testing.T) {
+	a := assert.New(t)
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix shell only")
+	}
+
+	decode := func(requires string) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.Decode([]byte(`
+inputs:
+  - name: minVersion
+    default: "1.2"
+envs:
+  STAGE: requires
+requires:
+`+requires+`
+executes:
+  afterEnvs:
+    - touch afterEnvs.txt
+  pre:
+    - touch pre.txt
+`), nil)
+	}
+
+	parser, err := decode(`
+  - sh
+  - name: sh
+    version: '>={{ .Inputs.minVersion }}'
+    versionCommand: echo "tool version v1.2.3 (build 7)"
+  - name: definitely-missing-tool
+    optional: true
+`)
+	if a.NoError(err) {
+		a.FileExists(filepath.Join(parser.WorkerPath, "afterEnvs.txt"))
+		a.FileExists(filepath.Join(parser.WorkerPath, "pre.txt"))
+	}
+
+	parser, err = decode(`
+  - name: sh
+    version: ^2.0
+    versionCommand: echo "tool 1.8.0_292"
+  - name: definitely-missing-tool
+    hint: 请先安装definitely-missing-tool
+`)
+	if a.Error(err) {
+		a.Contains(err.Error(), "[sh]: 版本[1.8.0]不满足[^2.0]")
+		a.Contains(err.Error(), "[definitely-missing-tool]: 未找到可执行文件, 请先安装definitely-missing-tool")
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "afterEnvs.txt"))
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "pre.txt"))
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	var confirmed []string
+	err = parser.SetExecutePolicy(&ExecutePolicy{
+		Confirm: func(info *ExecConfirmInfo) (bool, error) {
+			confirmed = append(confirmed, info.Command)
+			return false, nil
+		},
+	}).Decode([]byte(`
+requires:
+  - name: sh
+    version: ">=1"
+    versionCommand: touch pwned2
+`), nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "[sh]: 命令未被确认, 拒绝执行: touch pwned2")
+		a.Equal([]string{"touch pwned2"}, confirmed)
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "pwned2"))
+	}
+}
@@ -0,0 +1,321 @@
+package templateparser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FixtureMode 动态变量请求录制/回放模式
+type FixtureMode string
+
+const (
+	// FixtureModeNone 不录制也不回放
+	FixtureModeNone FixtureMode = ""
+	// FixtureModeRecord 录制模式, 请求正常发送并将请求与响应保存至录制目录
+	FixtureModeRecord FixtureMode = "record"
+	// FixtureModeReplay 回放模式, 从录制目录读取响应, 未录制的请求直接失败
+	FixtureModeReplay FixtureMode = "replay"
+)
+
+// redactedHeaders 录制时隐藏的敏感请求头
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// fixtureRequest 录制的请求
+type fixtureRequest struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// fixtureResponse 录制的响应
+type fixtureResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Headers    http.Header `json:"headers,omitempty"`
+}
+
+// fixtureRecord 录制文件内容, 响应体单独保存在同名的.body文件中
+type fixtureRecord struct {
+	Request  *fixtureRequest  `json:"request"`
+	Response *fixtureResponse `json:"response"`
+}
+
+// fixtureTransport 录制/回放动态变量请求的transport
+type fixtureTransport struct {
+	base    http.RoundTripper
+	mode    FixtureMode
+	dirPath string
+	name    string
+}
+
+// SetFixtureMode 设置动态变量请求的录制/回放模式及录制目录
+func (p *Parser) SetFixtureMode(mode FixtureMode, dirPath string) *Parser {
+	p.fixtureMode = mode
+	p.fixtureDirPath = dirPath
+	return p
+}
+
+// SetRecordMode 录制动态变量的请求与响应至指定目录
+func (p *Parser) SetRecordMode(dirPath string) *Parser {
+	return p.SetFixtureMode(FixtureModeRecord, dirPath)
+}
+
+// SetReplayMode 从指定目录回放动态变量的响应, 未录制的请求将失败
+func (p *Parser) SetReplayMode(dirPath string) *Parser {
+	return p.SetFixtureMode(FixtureModeReplay, dirPath)
+}
+
+// wrapFixtureTransport 按录制/回放模式包装transport
+func (p *Parser) wrapFixtureTransport(name string, transport http.RoundTripper) http.RoundTripper {
+	if p.fixtureMode == FixtureModeNone {
+		return transport
+	}
+
+	return &fixtureTransport{
+		base:    transport,
+		mode:    p.fixtureMode,
+		dirPath: filepath.Join(p.fixtureDirPath, name),
+		name:    name,
+	}
+}
+
+// fixtureKey 通过请求方式、地址与请求体生成录制标识
+func fixtureKey(method, url, contentType string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + url + "\n"))
+	if digest, ok := multipartDigest(contentType, body); ok {
+		h.Write(digest)
+	} else {
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// multipartDigest multipart请求体的分隔符每次随机生成, 按各部分的头信息与内容计算摘要, 非multipart或格式错误时返回false
+func multipartDigest(contentType string, body []byte) ([]byte, bool) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return nil, false
+	}
+
+	h := sha256.New()
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			return h.Sum(nil), true
+		} else if err != nil {
+			return nil, false
+		}
+
+		keys := make([]string, 0, len(part.Header))
+		for k := range part.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			h.Write([]byte(k + ": " + strings.Join(part.Header[k], ", ") + "\n"))
+		}
+		h.Write([]byte("\n"))
+		if _, err = io.Copy(h, part); err != nil {
+			return nil, false
+		}
+		h.Write([]byte("\n"))
+	}
+}
+
+func (f *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	url := req.URL.String()
+	key := fixtureKey(req.Method, url, req.Header.Get("Content-Type"), body)
+	recordPath := filepath.Join(f.dirPath, key+".json")
+	bodyPath := filepath.Join(f.dirPath, key+".body")
+
+	if f.mode == FixtureModeReplay {
+		return f.replay(req, recordPath, bodyPath)
+	}
+
+	res, err := f.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := req.Header.Clone()
+	for _, h := range redactedHeaders {
+		if headers.Get(h) != "" {
+			headers.Set(h, "***")
+		}
+	}
+
+	record := &fixtureRecord{
+		Request: &fixtureRequest{
+			Method:  req.Method,
+			Url:     url,
+			Headers: headers,
+			Body:    string(body),
+		},
+		Response: &fixtureResponse{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Headers:    res.Header,
+		},
+	}
+
+	marshal, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, err
+	}
+
+	if err = os.MkdirAll(f.dirPath, 0777); err != nil {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("remoteVars[%s]: 创建录制目录失败: %w", f.name, err)
+	}
+	file, err := os.Create(bodyPath + ".tmp")
+	if err != nil {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("remoteVars[%s]: 保存录制响应失败: %w", f.name, err)
+	}
+
+	res.Body = &fixtureBody{
+		body: res.Body,
+		tee:  io.TeeReader(res.Body, file),
+		file: file,
+		save: func() error {
+			if err := os.Rename(file.Name(), bodyPath); err != nil {
+				return fmt.Errorf("remoteVars[%s]: 保存录制响应失败: %w", f.name, err)
+			}
+			if err := os.WriteFile(recordPath, marshal, 0666); err != nil {
+				return fmt.Errorf("remoteVars[%s]: 保存录制请求失败: %w", f.name, err)
+			}
+			return nil
+		},
+	}
+	return res, nil
+}
+
+// fixtureBody 读取响应体的同时写入录制文件, 读取完成后保存录制请求, 未保存完成的录制不会被回放
+type fixtureBody struct {
+	body io.ReadCloser
+	tee  io.Reader
+	file *os.File
+	save func() error
+	done bool
+	err  error
+}
+
+func (b *fixtureBody) Read(p []byte) (int, error) {
+	if b.done {
+		if b.err != nil {
+			return 0, b.err
+		}
+		return 0, io.EOF
+	}
+
+	n, err := b.tee.Read(p)
+	if err == io.EOF {
+		if err = b.finish(nil); err == nil {
+			err = io.EOF
+		}
+	} else if err != nil {
+		_ = b.finish(err)
+	}
+	return n, err
+}
+
+// Close 未读取完的响应体同样写入录制文件, 保证录制完整
+func (b *fixtureBody) Close() error {
+	if !b.done {
+		_, err := io.Copy(io.Discard, b.tee)
+		_ = b.finish(err)
+	}
+	_ = b.body.Close()
+	return b.err
+}
+
+// finish 关闭录制文件, 读取成功时保存录制, 失败时删除录制文件
+func (b *fixtureBody) finish(readErr error) error {
+	b.done = true
+	err := b.file.Close()
+	if readErr != nil {
+		err = readErr
+	}
+	if err == nil {
+		err = b.save()
+	}
+	if err != nil {
+		_ = os.Remove(b.file.Name())
+	}
+	b.err = err
+	return err
+}
+
+// replay 回放录制的响应
+func (f *fixtureTransport) replay(req *http.Request, recordPath, bodyPath string) (*http.Response, error) {
+	content, err := os.ReadFile(recordPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("remoteVars[%s]: 未找到录制的请求: %s %s", f.name, req.Method, req.URL.String())
+	} else if err != nil {
+		return nil, fmt.Errorf("remoteVars[%s]: 读取录制请求失败: %w", f.name, err)
+	}
+
+	record := &fixtureRecord{}
+	if err = json.Unmarshal(content, record); err != nil || record.Response == nil {
+		return nil, fmt.Errorf("remoteVars[%s]: 错误的录制文件[%s]", f.name, recordPath)
+	}
+
+	resBody, err := os.Open(bodyPath)
+	if err != nil {
+		return nil, fmt.Errorf("remoteVars[%s]: 读取录制响应失败: %w", f.name, err)
+	}
+	stat, err := resBody.Stat()
+	if err != nil {
+		_ = resBody.Close()
+		return nil, fmt.Errorf("remoteVars[%s]: 读取录制响应失败: %w", f.name, err)
+	}
+
+	status := record.Response.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", record.Response.StatusCode, http.StatusText(record.Response.StatusCode))
+	}
+
+	headers := record.Response.Headers
+	if headers == nil {
+		headers = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        status,
+		StatusCode:    record.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          resBody,
+		ContentLength: stat.Size(),
+		Request:       req,
+	}, nil
+}
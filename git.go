@@ -0,0 +1,800 @@
+package templateparser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	gitObjectCommit   = "commit"
+	gitObjectTree     = "tree"
+	gitObjectBlob     = "blob"
+	gitObjectTag      = "tag"
+	gitPackOfsDelta   = 6
+	gitPackRefDelta   = 7
+	gitMaxDeltaDepth  = 64
+	gitPackIdxVersion = 2
+	// gitSha1Size SHA-1对象哈希的字节数
+	gitSha1Size = 20
+	// gitSha256Size SHA-256对象哈希的字节数, 仓库配置 `extensions.objectFormat = sha256` 时使用
+	gitSha256Size = 32
+)
+
+var gitPackIdxMagic = []byte{0xff, 't', 'O', 'c'}
+
+// gitRepository 只读的本地git仓库
+type gitRepository struct {
+	// workPath 工作目录
+	workPath string
+	// gitDir 仓库目录(.git)
+	gitDir string
+	// commonDir 公共目录, worktree时与gitDir不同
+	commonDir string
+	// packedRefs packed-refs中的引用
+	packedRefs map[string]string
+	// packedPeeled packed-refs中附注标签指向的对象
+	packedPeeled map[string]string
+	// hashSize 对象哈希的字节数
+	hashSize int
+	// packIndexes pack索引, 首次查找pack对象时加载
+	packIndexes []*gitPackIndex
+}
+
+// gitPackIndex 已加载的v2版本pack索引
+type gitPackIndex struct {
+	// packPath pack文件路径
+	packPath string
+	// fanout 首字节不大于下标的对象数量
+	fanout [256]uint32
+	// hashes 排序后的对象哈希
+	hashes []byte
+	// offsets 对象在pack文件中的偏移量
+	offsets []byte
+	// largeOffsets 超过31位的偏移量
+	largeOffsets []byte
+}
+
+// GitSignature 提交签名
+type GitSignature struct {
+	// Name 名称
+	Name string
+	// Email 邮箱
+	Email string
+	// Time 时间
+	Time time.Time
+}
+
+// GitCommitInfo 提交信息
+type GitCommitInfo struct {
+	// Hash 提交哈希
+	Hash string
+	// ShortHash 短哈希
+	ShortHash string
+	// Tree 树对象哈希
+	Tree string
+	// Parents 父提交
+	Parents []string
+	// Author 作者
+	Author *GitSignature
+	// Committer 提交者
+	Committer *GitSignature
+	// Subject 提交标题
+	Subject string
+	// Message 完整提交信息
+	Message string
+}
+
+// GitTagInfo 标签信息
+type GitTagInfo struct {
+	// Name 标签名称
+	Name string
+	// Hash 标签指向的提交哈希
+	Hash string
+	// Annotated 是否为附注标签
+	Annotated bool
+	// Message 附注信息
+	Message string
+	// Time 提交时间
+	Time time.Time
+}
+
+// GitRemoteInfo 远程仓库信息
+type GitRemoteInfo struct {
+	// Name 名称
+	Name string
+	// Url 地址
+	Url string
+	// Fetch 拉取规则, 按配置顺序保存所有规则
+	Fetch []string
+}
+
+// GitRepositoryInfo 仓库元数据
+type GitRepositoryInfo struct {
+	// Path 仓库工作目录
+	Path string
+	// Head HEAD指向的提交哈希
+	Head string
+	// Branch 当前分支, 游离状态下为空
+	Branch string
+	// Detached 是否为游离HEAD
+	Detached bool
+	// Commit HEAD提交信息
+	Commit *GitCommitInfo
+	// Branches 本地分支列表
+	Branches []string
+	// Tags 标签列表, 按提交时间倒序
+	Tags []*GitTagInfo
+	// LatestTag 最新标签名称
+	LatestTag string
+	// Remotes 远程仓库
+	Remotes map[string]*GitRemoteInfo
+	// Config 配置, key格式为: section.subsection.key, 多值的key与 `git config --get` 一致取最后一个值
+	Config map[string]string
+	// ConfigValues 配置的所有值, 多值的key(例: remote.origin.fetch)按出现顺序保存
+	ConfigValues map[string][]string
+}
+
+func openGitRepository(path string) (*gitRepository, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := &gitRepository{workPath: path}
+	dotGit := filepath.Join(path, ".git")
+	stat, err := os.Stat(dotGit)
+	switch {
+	case err == nil && stat.IsDir():
+		repo.gitDir = dotGit
+	case err == nil:
+		content, err := os.ReadFile(dotGit)
+		if err != nil {
+			return nil, err
+		}
+		gitDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(content)), "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(path, gitDir)
+		}
+		repo.gitDir = gitDir
+	default:
+		if _, err = os.Stat(filepath.Join(path, "HEAD")); err != nil {
+			return nil, fmt.Errorf("[%s]不是git仓库", path)
+		}
+		repo.gitDir = path
+	}
+
+	repo.commonDir = repo.gitDir
+	if content, err := os.ReadFile(filepath.Join(repo.gitDir, "commondir")); err == nil {
+		commonDir := strings.TrimSpace(string(content))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(repo.gitDir, commonDir)
+		}
+		repo.commonDir = commonDir
+	}
+
+	if err = repo.loadPackedRefs(); err != nil {
+		return nil, err
+	}
+
+	config, err := repo.readConfig()
+	if err != nil {
+		return nil, err
+	}
+	switch format := strings.ToLower(lastGitConfigValue(config["extensions.objectformat"])); format {
+	case "", "sha1":
+		repo.hashSize = gitSha1Size
+	case "sha256":
+		repo.hashSize = gitSha256Size
+	default:
+		return nil, fmt.Errorf("不支持的对象格式: %s", format)
+	}
+
+	return repo, nil
+}
+
+// loadPackedRefs 加载packed-refs
+func (r *gitRepository) loadPackedRefs() error {
+	r.packedRefs = make(map[string]string)
+	r.packedPeeled = make(map[string]string)
+
+	file, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	prevRef := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "^") {
+			if prevRef != "" {
+				r.packedPeeled[prevRef] = line[1:]
+			}
+			continue
+		}
+
+		hash, ref, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		r.packedRefs[ref] = hash
+		prevRef = ref
+	}
+	return scanner.Err()
+}
+
+// readHead 读取HEAD, 返回引用名称(游离状态为空)与提交哈希
+func (r *gitRepository) readHead() (ref string, hash string, err error) {
+	content, err := os.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+	if err != nil {
+		return "", "", fmt.Errorf("读取HEAD失败: %w", err)
+	}
+
+	head := strings.TrimSpace(string(content))
+	if !strings.HasPrefix(head, "ref:") {
+		return "", head, nil
+	}
+
+	ref = strings.TrimSpace(strings.TrimPrefix(head, "ref:"))
+	hash, _ = r.resolveRef(ref)
+	return ref, hash, nil
+}
+
+// resolveRef 解析引用对应的哈希
+func (r *gitRepository) resolveRef(ref string) (string, error) {
+	for i := 0; i < 10; i++ {
+		content, err := os.ReadFile(filepath.Join(r.commonDir, filepath.FromSlash(ref)))
+		if err != nil {
+			hash, ok := r.packedRefs[ref]
+			if !ok {
+				return "", fmt.Errorf("引用[%s]不存在", ref)
+			}
+			return hash, nil
+		}
+
+		val := strings.TrimSpace(string(content))
+		if !strings.HasPrefix(val, "ref:") {
+			return val, nil
+		}
+		ref = strings.TrimSpace(strings.TrimPrefix(val, "ref:"))
+	}
+	return "", fmt.Errorf("引用[%s]嵌套层级过深", ref)
+}
+
+// listRefs 列出指定前缀下的引用, key为去除前缀的名称
+func (r *gitRepository) listRefs(prefix string) (map[string]string, error) {
+	result := make(map[string]string)
+	for ref, hash := range r.packedRefs {
+		if strings.HasPrefix(ref, prefix) {
+			result[strings.TrimPrefix(ref, prefix)] = hash
+		}
+	}
+
+	root := filepath.Join(r.commonDir, filepath.FromSlash(prefix))
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		hash, err := r.resolveRef(prefix + filepath.ToSlash(rel))
+		if err != nil {
+			return nil
+		}
+		result[filepath.ToSlash(rel)] = hash
+		return nil
+	})
+	return result, err
+}
+
+// readObject 读取对象, 返回对象类型与内容
+func (r *gitRepository) readObject(hash string) (string, []byte, error) {
+	if len(hash) != r.hashSize*2 {
+		return "", nil, fmt.Errorf("错误的对象哈希: %s", hash)
+	}
+
+	objType, content, err := r.readLooseObject(hash)
+	if err == nil {
+		return objType, content, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", nil, err
+	}
+
+	return r.readPackedObject(hash, 0)
+}
+
+func (r *gitRepository) readLooseObject(hash string) (string, []byte, error) {
+	file, err := os.Open(filepath.Join(r.commonDir, "objects", hash[:2], hash[2:]))
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	zr, err := zlib.NewReader(file)
+	if err != nil {
+		return "", nil, fmt.Errorf("解压对象[%s]失败: %w", hash, err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("解压对象[%s]失败: %w", hash, err)
+	}
+
+	header, content, ok := bytes.Cut(raw, []byte{0})
+	if !ok {
+		return "", nil, fmt.Errorf("错误的对象格式: %s", hash)
+	}
+
+	objType, _, _ := strings.Cut(string(header), " ")
+	return objType, content, nil
+}
+
+func (r *gitRepository) readPackedObject(hash string, depth int) (string, []byte, error) {
+	if depth > gitMaxDeltaDepth {
+		return "", nil, fmt.Errorf("对象[%s]差异链过长", hash)
+	}
+
+	hashBytes, err := hex.DecodeString(hash)
+	if err != nil {
+		return "", nil, fmt.Errorf("错误的对象哈希: %s", hash)
+	}
+
+	if r.packIndexes == nil {
+		if err = r.loadPackIndexes(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	for _, index := range r.packIndexes {
+		offset, ok, err := index.find(hashBytes, r.hashSize)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			continue
+		}
+		return r.readPackObjectAt(index.packPath, offset, depth)
+	}
+
+	return "", nil, fmt.Errorf("对象[%s]不存在", hash)
+}
+
+// loadPackIndexes 加载所有pack索引, 同一仓库仅加载一次
+func (r *gitRepository) loadPackIndexes() error {
+	idxList, _ := filepath.Glob(filepath.Join(r.commonDir, "objects", "pack", "*.idx"))
+	r.packIndexes = make([]*gitPackIndex, 0, len(idxList))
+	for _, idxPath := range idxList {
+		index, err := loadGitPackIndex(idxPath, r.hashSize)
+		if err != nil {
+			return err
+		}
+		r.packIndexes = append(r.packIndexes, index)
+	}
+	return nil
+}
+
+// loadGitPackIndex 读取v2版本的pack索引
+func loadGitPackIndex(idxPath string, hashSize int) (*gitPackIndex, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := fmt.Errorf("不支持的pack索引格式: %s", idxPath)
+	if len(idx) < 8+256*4 || !bytes.Equal(idx[:4], gitPackIdxMagic) || binary.BigEndian.Uint32(idx[4:8]) != gitPackIdxVersion {
+		return nil, invalid
+	}
+
+	index := &gitPackIndex{packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack"}
+	for i := range index.fanout {
+		index.fanout[i] = binary.BigEndian.Uint32(idx[8+i*4:])
+	}
+
+	total := int(index.fanout[255])
+	hashTable := 8 + 256*4
+	offsetTable := hashTable + total*hashSize + total*4
+	largeTable := offsetTable + total*4
+	if len(idx) < largeTable {
+		return nil, invalid
+	}
+	index.hashes = idx[hashTable : hashTable+total*hashSize]
+	index.offsets = idx[offsetTable:largeTable]
+	index.largeOffsets = idx[largeTable:]
+	return index, nil
+}
+
+// find 查找对象在pack文件中的偏移量
+func (i *gitPackIndex) find(hash []byte, hashSize int) (int64, bool, error) {
+	start := 0
+	if hash[0] > 0 {
+		start = int(i.fanout[hash[0]-1])
+	}
+	end := int(i.fanout[hash[0]])
+
+	n := start + sort.Search(end-start, func(n int) bool {
+		pos := (start + n) * hashSize
+		return bytes.Compare(i.hashes[pos:pos+hashSize], hash) >= 0
+	})
+	if n >= end || !bytes.Equal(i.hashes[n*hashSize:(n+1)*hashSize], hash) {
+		return 0, false, nil
+	}
+
+	offset := int64(binary.BigEndian.Uint32(i.offsets[n*4:]))
+	if offset&0x80000000 != 0 {
+		pos := int(offset&0x7fffffff) * 8
+		if pos+8 > len(i.largeOffsets) {
+			return 0, false, fmt.Errorf("错误的pack索引偏移量: %s", i.packPath)
+		}
+		offset = int64(binary.BigEndian.Uint64(i.largeOffsets[pos : pos+8]))
+	}
+	return offset, true, nil
+}
+
+func (r *gitRepository) readPackObjectAt(packPath string, offset int64, depth int) (string, []byte, error) {
+	file, err := os.Open(packPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+	reader := bufio.NewReader(file)
+
+	b, err := reader.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	objType := (b >> 4) & 0x07
+	for b&0x80 != 0 {
+		if b, err = reader.ReadByte(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var (
+		baseType    string
+		baseContent []byte
+	)
+	switch objType {
+	case gitPackOfsDelta:
+		if b, err = reader.ReadByte(); err != nil {
+			return "", nil, err
+		}
+		rel := int64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = reader.ReadByte(); err != nil {
+				return "", nil, err
+			}
+			rel = ((rel + 1) << 7) | int64(b&0x7f)
+		}
+		if baseType, baseContent, err = r.readPackObjectAt(packPath, offset-rel, depth+1); err != nil {
+			return "", nil, err
+		}
+	case gitPackRefDelta:
+		baseHash := make([]byte, r.hashSize)
+		if _, err = io.ReadFull(reader, baseHash); err != nil {
+			return "", nil, err
+		}
+		if baseType, baseContent, err = r.readPackedObject(hex.EncodeToString(baseHash), depth+1); err != nil {
+			return "", nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(reader)
+	if err != nil {
+		return "", nil, fmt.Errorf("解压pack对象失败: %w", err)
+	}
+	defer zr.Close()
+
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("解压pack对象失败: %w", err)
+	}
+
+	switch objType {
+	case 1:
+		return gitObjectCommit, content, nil
+	case 2:
+		return gitObjectTree, content, nil
+	case 3:
+		return gitObjectBlob, content, nil
+	case 4:
+		return gitObjectTag, content, nil
+	case gitPackOfsDelta, gitPackRefDelta:
+		content, err = applyGitDelta(baseContent, content)
+		return baseType, content, err
+	default:
+		return "", nil, fmt.Errorf("不支持的pack对象类型: %d", objType)
+	}
+}
+
+// applyGitDelta 应用差异数据
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	readSize := func() int {
+		size, shift := 0, 0
+		for len(delta) > 0 {
+			b := delta[0]
+			delta = delta[1:]
+			size |= int(b&0x7f) << shift
+			shift += 7
+			if b&0x80 == 0 {
+				break
+			}
+		}
+		return size
+	}
+
+	if srcSize := readSize(); srcSize != len(base) {
+		return nil, errors.New("差异数据与源对象大小不符")
+	}
+	result := make([]byte, 0, readSize())
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			if op == 0 || int(op) > len(delta) {
+				return nil, errors.New("错误的差异数据")
+			}
+			result = append(result, delta[:op]...)
+			delta = delta[op:]
+			continue
+		}
+
+		var offset, size int
+		for i := 0; i < 4; i++ {
+			if op&(1<<i) != 0 {
+				if len(delta) == 0 {
+					return nil, errors.New("错误的差异数据")
+				}
+				offset |= int(delta[0]) << (8 * i)
+				delta = delta[1:]
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if op&(0x10<<i) != 0 {
+				if len(delta) == 0 {
+					return nil, errors.New("错误的差异数据")
+				}
+				size |= int(delta[0]) << (8 * i)
+				delta = delta[1:]
+			}
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+		if offset+size > len(base) {
+			return nil, errors.New("错误的差异数据")
+		}
+		result = append(result, base[offset:offset+size]...)
+	}
+	return result, nil
+}
+
+// parseGitSignature 解析签名, 格式: Name <email> 1672531200 +0800
+func parseGitSignature(str string) *GitSignature {
+	sign := &GitSignature{}
+	emailStart := strings.Index(str, "<")
+	emailEnd := strings.Index(str, ">")
+	if emailStart == -1 || emailEnd < emailStart {
+		sign.Name = strings.TrimSpace(str)
+		return sign
+	}
+
+	sign.Name = strings.TrimSpace(str[:emailStart])
+	sign.Email = str[emailStart+1 : emailEnd]
+
+	fields := strings.Fields(str[emailEnd+1:])
+	if len(fields) == 0 {
+		return sign
+	}
+	sec, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return sign
+	}
+	sign.Time = time.Unix(sec, 0)
+	if len(fields) > 1 && len(fields[1]) == 5 {
+		hours, _ := strconv.Atoi(fields[1][1:3])
+		minutes, _ := strconv.Atoi(fields[1][3:])
+		zoneOffset := hours*3600 + minutes*60
+		if fields[1][0] == '-' {
+			zoneOffset = -zoneOffset
+		}
+		sign.Time = sign.Time.In(time.FixedZone(fields[1], zoneOffset))
+	}
+	return sign
+}
+
+// parseGitObjectHeaders 解析提交/标签对象的头部与信息
+func parseGitObjectHeaders(content []byte) (map[string][]string, string) {
+	headers := make(map[string][]string)
+	headerPart, message, _ := strings.Cut(string(content), "\n\n")
+	for _, line := range strings.Split(headerPart, "\n") {
+		if strings.HasPrefix(line, " ") {
+			continue
+		}
+		k, v, _ := strings.Cut(line, " ")
+		headers[k] = append(headers[k], v)
+	}
+	return headers, message
+}
+
+// readCommit 读取提交信息
+func (r *gitRepository) readCommit(hash string) (*GitCommitInfo, error) {
+	objType, content, err := r.readObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if objType != gitObjectCommit {
+		return nil, fmt.Errorf("对象[%s]不是提交", hash)
+	}
+
+	headers, message := parseGitObjectHeaders(content)
+	commit := &GitCommitInfo{
+		Hash:      hash,
+		ShortHash: hash[:7],
+		Parents:   headers["parent"],
+		Message:   message,
+	}
+	commit.Subject, _, _ = strings.Cut(message, "\n")
+	if tree := headers["tree"]; len(tree) > 0 {
+		commit.Tree = tree[0]
+	}
+	if author := headers["author"]; len(author) > 0 {
+		commit.Author = parseGitSignature(author[0])
+	}
+	if committer := headers["committer"]; len(committer) > 0 {
+		commit.Committer = parseGitSignature(committer[0])
+	}
+	return commit, nil
+}
+
+// readTag 读取标签, 附注标签会解析至最终指向的提交.
+// packed-refs中已记录最终指向的对象时, 仅读取标签对象获取附注信息
+func (r *gitRepository) readTag(name, hash string) (*GitTagInfo, error) {
+	tag := &GitTagInfo{Name: name, Hash: hash}
+	peeled, hasPeeled := r.packedPeeled["refs/tags/"+name]
+
+	for i := 0; i < 10; i++ {
+		objType, content, err := r.readObject(tag.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if objType != gitObjectTag {
+			if objType == gitObjectCommit {
+				headers, _ := parseGitObjectHeaders(content)
+				if committer := headers["committer"]; len(committer) > 0 {
+					tag.Time = parseGitSignature(committer[0]).Time
+				}
+			}
+			return tag, nil
+		}
+
+		headers, message := parseGitObjectHeaders(content)
+		tag.Annotated = true
+		if tag.Message == "" {
+			tag.Message = message
+		}
+		if hasPeeled {
+			tag.Hash, hasPeeled = peeled, false
+		} else if object := headers["object"]; len(object) > 0 {
+			tag.Hash = object[0]
+		}
+	}
+	return nil, fmt.Errorf("标签[%s]嵌套层级过深", name)
+}
+
+// Info 读取仓库元数据
+func (r *gitRepository) Info() (*GitRepositoryInfo, error) {
+	info := &GitRepositoryInfo{
+		Path:    r.workPath,
+		Remotes: make(map[string]*GitRemoteInfo),
+	}
+
+	ref, hash, err := r.readHead()
+	if err != nil {
+		return nil, err
+	}
+	info.Head = hash
+	info.Detached = ref == ""
+	info.Branch = strings.TrimPrefix(ref, "refs/heads/")
+	if hash != "" {
+		if info.Commit, err = r.readCommit(hash); err != nil {
+			return nil, err
+		}
+	}
+
+	branches, err := r.listRefs("refs/heads/")
+	if err != nil {
+		return nil, err
+	}
+	info.Branches = make([]string, 0, len(branches))
+	for name := range branches {
+		info.Branches = append(info.Branches, name)
+	}
+	sort.Strings(info.Branches)
+
+	tags, err := r.listRefs("refs/tags/")
+	if err != nil {
+		return nil, err
+	}
+	info.Tags = make([]*GitTagInfo, 0, len(tags))
+	for name, tagHash := range tags {
+		tag, err := r.readTag(name, tagHash)
+		if err != nil {
+			return nil, err
+		}
+		info.Tags = append(info.Tags, tag)
+	}
+	sort.Slice(info.Tags, func(i, j int) bool {
+		if info.Tags[i].Time.Equal(info.Tags[j].Time) {
+			return info.Tags[i].Name > info.Tags[j].Name
+		}
+		return info.Tags[i].Time.After(info.Tags[j].Time)
+	})
+	if len(info.Tags) > 0 {
+		info.LatestTag = info.Tags[0].Name
+	}
+
+	if info.ConfigValues, err = r.readConfig(); err != nil {
+		return nil, err
+	}
+	info.Config = make(map[string]string, len(info.ConfigValues))
+	for k, values := range info.ConfigValues {
+		info.Config[k] = lastGitConfigValue(values)
+		if !strings.HasPrefix(k, "remote.") {
+			continue
+		}
+		name := strings.TrimPrefix(k, "remote.")
+		index := strings.LastIndex(name, ".")
+		if index == -1 {
+			continue
+		}
+		name, key := name[:index], name[index+1:]
+		remote, ok := info.Remotes[name]
+		if !ok {
+			remote = &GitRemoteInfo{Name: name}
+			info.Remotes[name] = remote
+		}
+		switch key {
+		case "url":
+			remote.Url = lastGitConfigValue(values)
+		case "fetch":
+			remote.Fetch = values
+		}
+	}
+
+	return info, nil
+}
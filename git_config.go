@@ -0,0 +1,355 @@
+package templateparser
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitConfigMaxIncludeDepth 配置文件最大包含层级, 与git一致
+const gitConfigMaxIncludeDepth = 10
+
+// gitConfigEntry 配置项, key格式为: section.subsection.key
+type gitConfigEntry struct {
+	key   string
+	value string
+}
+
+// readConfig 读取仓库配置, 多值的key按出现顺序保存所有值, 支持通过include及includeIf(gitdir、gitdir/i、onbranch条件)包含其他配置文件
+func (r *gitRepository) readConfig() (map[string][]string, error) {
+	result := make(map[string][]string)
+	if err := r.readConfigFile(filepath.Join(r.commonDir, "config"), result, 0); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readConfigFile 读取配置文件, 包含的配置文件在include所在位置读取, 其中的值覆盖之前的值
+func (r *gitRepository) readConfigFile(path string, result map[string][]string, depth int) error {
+	if depth > gitConfigMaxIncludeDepth {
+		return fmt.Errorf("配置文件[%s]包含层级过深", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	entries, err := parseGitConfig(string(content))
+	if err != nil {
+		return fmt.Errorf("解析配置文件[%s]失败: %w", path, err)
+	}
+
+	for _, entry := range entries {
+		result[entry.key] = append(result[entry.key], entry.value)
+
+		include, err := r.configInclude(entry, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if include == "" {
+			continue
+		}
+		if err = r.readConfigFile(include, result, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configInclude 获取配置项包含的配置文件路径, 非包含配置或条件不满足时返回空字符串
+func (r *gitRepository) configInclude(entry gitConfigEntry, dir string) (string, error) {
+	if !strings.HasSuffix(entry.key, ".path") || entry.value == "" {
+		return "", nil
+	}
+
+	section := strings.TrimSuffix(entry.key, ".path")
+	if section != "include" {
+		if !strings.HasPrefix(section, "includeif.") {
+			return "", nil
+		}
+		matched, err := r.matchIncludeCondition(strings.TrimPrefix(section, "includeif."), dir)
+		if err != nil || !matched {
+			return "", err
+		}
+	}
+
+	path := expandGitConfigPath(entry.value)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return path, nil
+}
+
+// matchIncludeCondition 判断includeIf的条件是否满足, 不支持的条件(例: hasconfig)视为不满足
+func (r *gitRepository) matchIncludeCondition(condition, dir string) (bool, error) {
+	kind, pattern, ok := strings.Cut(condition, ":")
+	if !ok {
+		return false, nil
+	}
+
+	switch kind {
+	case "gitdir", "gitdir/i":
+		pattern = expandGitConfigPath(pattern)
+		if strings.HasPrefix(pattern, "./") {
+			pattern = filepath.Join(dir, pattern[2:])
+		}
+		pattern = filepath.ToSlash(pattern)
+		if !strings.HasPrefix(pattern, "/") && !filepath.IsAbs(pattern) {
+			pattern = "**/" + pattern
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+
+		gitDirs := []string{r.gitDir}
+		if realPath, err := filepath.EvalSymlinks(r.gitDir); err == nil && realPath != r.gitDir {
+			gitDirs = append(gitDirs, realPath)
+		}
+		for _, gitDir := range gitDirs {
+			if matchGitConfigGlob(pattern, filepath.ToSlash(gitDir), kind == "gitdir/i") {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "onbranch":
+		ref, _, err := r.readHead()
+		if err != nil {
+			return false, err
+		}
+		if !strings.HasPrefix(ref, "refs/heads/") {
+			return false, nil
+		}
+		if strings.HasSuffix(pattern, "/") {
+			pattern += "**"
+		}
+		return matchGitConfigGlob(pattern, strings.TrimPrefix(ref, "refs/heads/"), false), nil
+	default:
+		return false, nil
+	}
+}
+
+// expandGitConfigPath 展开以 `~/` 开头的路径
+func expandGitConfigPath(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// matchGitConfigGlob 按git的通配规则匹配, `**/` 匹配任意层级目录, `*`、`?` 不匹配 `/`
+func matchGitConfigGlob(pattern, str string, foldCase bool) bool {
+	var b strings.Builder
+	if foldCase {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		case pattern[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return false
+	}
+	return re.MatchString(str)
+}
+
+// lastGitConfigValue 获取多值配置的最后一个值
+func lastGitConfigValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// parseGitConfig 按git配置文件语法解析配置项, 节名称与key不区分大小写, 子节名称区分大小写
+func parseGitConfig(content string) ([]gitConfigEntry, error) {
+	var (
+		entries []gitConfigEntry
+		section string
+	)
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for n := 0; n < len(lines); n++ {
+		line := strings.TrimLeft(lines[n], " \t")
+		if strings.HasPrefix(line, "[") {
+			name, end, err := parseGitConfigSection(line)
+			if err != nil {
+				return nil, fmt.Errorf("行: %d, %w", n+1, err)
+			}
+			section = name
+			line = strings.TrimLeft(line[end:], " \t")
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		i := 0
+		for i < len(line) && (isGitConfigNameChar(line[i]) || line[i] == '-') {
+			i++
+		}
+		if i == 0 || section == "" {
+			return nil, fmt.Errorf("行: %d, 错误的配置项: %s", n+1, line)
+		}
+		key := section + "." + strings.ToLower(line[:i])
+
+		rest := strings.TrimLeft(line[i:], " \t")
+		if rest == "" || rest[0] == '#' || rest[0] == ';' {
+			// 没有值的key为布尔值true
+			entries = append(entries, gitConfigEntry{key: key, value: "true"})
+			continue
+		}
+		if rest[0] != '=' {
+			return nil, fmt.Errorf("行: %d, 错误的配置项: %s", n+1, line)
+		}
+
+		lines[n] = rest[1:]
+		value, consumed, err := parseGitConfigValue(lines[n:])
+		if err != nil {
+			return nil, fmt.Errorf("行: %d, %w", n+1, err)
+		}
+		n += consumed
+		entries = append(entries, gitConfigEntry{key: key, value: value})
+	}
+	return entries, nil
+}
+
+// parseGitConfigSection 解析节名称, 返回 `section` 或 `section.subsection` 及 `]` 之后的位置.
+// 子节名称可使用 `\"`、`\\` 转义, 旧格式 [section.subsection] 整体不区分大小写
+func parseGitConfigSection(line string) (string, int, error) {
+	i := 1
+	for i < len(line) && (isGitConfigNameChar(line[i]) || line[i] == '-' || line[i] == '.') {
+		i++
+	}
+	name := strings.ToLower(line[1:i])
+	if name == "" {
+		return "", 0, fmt.Errorf("错误的节名称: %s", line)
+	}
+
+	if i < len(line) && line[i] == ']' {
+		return name, i + 1, nil
+	}
+
+	for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		i++
+	}
+	if i >= len(line) || line[i] != '"' {
+		return "", 0, fmt.Errorf("错误的节名称: %s", line)
+	}
+
+	var sub strings.Builder
+	for i++; i < len(line); i++ {
+		c := line[i]
+		if c == '"' {
+			if i+1 < len(line) && line[i+1] == ']' {
+				return name + "." + sub.String(), i + 2, nil
+			}
+			break
+		}
+		if c == '\\' && i+1 < len(line) {
+			i++
+			c = line[i]
+		}
+		sub.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("错误的节名称: %s", line)
+}
+
+// parseGitConfigValue 解析 `=` 之后的值, lines[0]为当前行 `=` 之后的内容, 以 `\` 结尾时与下一行拼接.
+// 引号外的 `#`、`;` 之后为注释, 首尾空白被去除, 中间的空白按git规则转换为空格, 返回值及拼接的行数
+func parseGitConfigValue(lines []string) (string, int, error) {
+	var (
+		b      strings.Builder
+		space  int
+		quoted bool
+	)
+
+	for n := 0; n < len(lines); n++ {
+		line := lines[n]
+		continued := false
+
+	scan:
+		for i := 0; i < len(line); i++ {
+			c := line[i]
+			if !quoted {
+				if c == ' ' || c == '\t' {
+					if b.Len() > 0 {
+						space++
+					}
+					continue
+				}
+				if c == '#' || c == ';' {
+					break scan
+				}
+			}
+
+			for ; space > 0; space-- {
+				b.WriteByte(' ')
+			}
+
+			switch c {
+			case '\\':
+				if i+1 == len(line) {
+					continued = true
+					break scan
+				}
+				i++
+				switch line[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case 'b':
+					b.WriteByte('\b')
+				case '\\', '"':
+					b.WriteByte(line[i])
+				default:
+					return "", 0, fmt.Errorf("错误的转义字符: \\%c", line[i])
+				}
+			case '"':
+				quoted = !quoted
+			default:
+				b.WriteByte(c)
+			}
+		}
+
+		if continued {
+			continue
+		}
+		if quoted {
+			return "", 0, errors.New("引号未结束")
+		}
+		return b.String(), n, nil
+	}
+	return "", 0, errors.New("续行未结束")
+}
+
+// isGitConfigNameChar 是否为节名称及key允许的字母或数字
+func isGitConfigNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,322 @@
+package templateparser
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InputType 输入参数类型
+type InputType string
+
+const (
+	InputTypeString InputType = "string"
+	InputTypeInt    InputType = "int"
+	InputTypeBool   InputType = "bool"
+	InputTypeEnum   InputType = "enum"
+	InputTypeList   InputType = "list"
+)
+
+// InputInfo 模板声明的输入参数
+type InputInfo struct {
+	// Name 参数名称, 模板中通过 `.Inputs.名称` 获取
+	Name string `yaml:"name"`
+	// Type 参数类型: string(默认) | int | bool | enum | list(字符串列表)
+	Type InputType `yaml:"type,omitempty"`
+	// Default 默认值
+	Default interface{} `yaml:"default,omitempty"`
+	// Enum 可选值, enum类型必填, string与list类型配置后同样校验
+	Enum []string `yaml:"enum,omitempty"`
+	// Pattern 正则校验, 作用于string、enum类型的值及list类型的每一项, 完整匹配需自行添加^与$
+	Pattern string `yaml:"pattern,omitempty"`
+	// Min 最小值, int类型为数值, string类型为长度, list类型为元素数量
+	Min *int `yaml:"min,omitempty"`
+	// Max 最大值, int类型为数值, string类型为长度, list类型为元素数量
+	Max *int `yaml:"max,omitempty"`
+	// Title 参数标题, 默认为参数名称
+	Title string `yaml:"title,omitempty"`
+	// Description 参数描述
+	Description string `yaml:"description,omitempty"`
+	// Required 是否必填, 必填且无默认值时未提供将报错
+	Required bool `yaml:"required,omitempty"`
+
+	line    int
+	pattern *regexp.Regexp
+}
+
+func (i *InputInfo) UnmarshalYAML(value *yaml.Node) error {
+	type inputInfo InputInfo
+	if err := value.Decode((*inputInfo)(i)); err != nil {
+		return err
+	}
+	i.line = value.Line
+
+	if strings.TrimSpace(i.Name) == "" {
+		return fmt.Errorf("行: %d, 列: %d, inputs缺失name(参数名称)", value.Line, value.Column)
+	}
+
+	switch i.Type {
+	case "":
+		i.Type = InputTypeString
+	case InputTypeString, InputTypeInt, InputTypeBool, InputTypeList:
+	case InputTypeEnum:
+		if len(i.Enum) == 0 {
+			return fmt.Errorf("行: %d, 列: %d, 输入参数[%s]为enum类型, 缺失enum(可选值)", value.Line, value.Column, i.Name)
+		}
+	default:
+		return fmt.Errorf("行: %d, 列: %d, 输入参数[%s]不支持的类型[%s]", value.Line, value.Column, i.Name, i.Type)
+	}
+
+	if i.Pattern != "" {
+		pattern, err := regexp.Compile(i.Pattern)
+		if err != nil {
+			return fmt.Errorf("行: %d, 列: %d, 输入参数[%s]错误的正则表达式: %w", value.Line, value.Column, i.Name, err)
+		}
+		i.pattern = pattern
+	}
+	return nil
+}
+
+// convert 将输入值转换为参数类型, 字符串形式的值(例如来自命令行)按类型解析
+func (i *InputInfo) convert(v interface{}) (interface{}, error) {
+	switch i.Type {
+	case InputTypeInt:
+		switch _v := v.(type) {
+		case int:
+			return _v, nil
+		case int64:
+			if _v > math.MaxInt || _v < math.MinInt {
+				return nil, fmt.Errorf("[%v]不是整数", _v)
+			}
+			return int(_v), nil
+		case uint64:
+			if _v > math.MaxInt {
+				return nil, fmt.Errorf("[%v]不是整数", _v)
+			}
+			return int(_v), nil
+		case float64:
+			// float64无法精确表示math.MaxInt, 使用 >= 2^63 判断溢出
+			if _v != math.Trunc(_v) || _v >= -float64(math.MinInt) || _v < float64(math.MinInt) {
+				return nil, fmt.Errorf("[%v]不是整数", _v)
+			}
+			return int(_v), nil
+		case string:
+			res, err := strconv.Atoi(strings.TrimSpace(_v))
+			if err != nil {
+				return nil, fmt.Errorf("[%s]不是整数", _v)
+			}
+			return res, nil
+		}
+	case InputTypeBool:
+		switch _v := v.(type) {
+		case bool:
+			return _v, nil
+		case string:
+			res, err := strconv.ParseBool(strings.TrimSpace(_v))
+			if err != nil {
+				return nil, fmt.Errorf("[%s]不是布尔值", _v)
+			}
+			return res, nil
+		}
+	case InputTypeList:
+		switch _v := v.(type) {
+		case []string:
+			return _v, nil
+		case []interface{}:
+			res := make([]string, 0, len(_v))
+			for _, item := range _v {
+				switch item.(type) {
+				case []interface{}, map[string]interface{}:
+					return nil, errors.New("列表元素应为字符串")
+				}
+				res = append(res, stringifyFieldValue(item))
+			}
+			return res, nil
+		case string:
+			res := make([]string, 0)
+			for _, item := range strings.Split(_v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					res = append(res, item)
+				}
+			}
+			return res, nil
+		}
+	default:
+		switch v.(type) {
+		case []string, []interface{}, map[string]interface{}:
+		default:
+			return stringifyFieldValue(v), nil
+		}
+	}
+	return nil, fmt.Errorf("类型应为%s", i.Type)
+}
+
+// validate 校验已转换类型的值
+func (i *InputInfo) validate(v interface{}) error {
+	var (
+		size   int
+		values []string
+	)
+	switch _v := v.(type) {
+	case int:
+		size = _v
+	case string:
+		size = len([]rune(_v))
+		values = []string{_v}
+	case []string:
+		size = len(_v)
+		values = _v
+	}
+
+	if i.Min != nil && size < *i.Min {
+		return fmt.Errorf("%s不能小于%d", i.sizeDesc(), *i.Min)
+	}
+	if i.Max != nil && size > *i.Max {
+		return fmt.Errorf("%s不能大于%d", i.sizeDesc(), *i.Max)
+	}
+
+	for _, value := range values {
+		if len(i.Enum) > 0 && !stringInSlice(value, i.Enum) {
+			return fmt.Errorf("[%s]不在可选值[%s]中", value, strings.Join(i.Enum, ", "))
+		}
+		if i.pattern != nil && !i.pattern.MatchString(value) {
+			return fmt.Errorf("[%s]不匹配[%s]", value, i.Pattern)
+		}
+	}
+	return nil
+}
+
+// sizeDesc 最小值与最大值的描述
+func (i *InputInfo) sizeDesc() string {
+	switch i.Type {
+	case InputTypeInt:
+		return "值"
+	case InputTypeList:
+		return "元素数量"
+	default:
+		return "长度"
+	}
+}
+
+// zero 类型的零值
+func (i *InputInfo) zero() interface{} {
+	switch i.Type {
+	case InputTypeInt:
+		return 0
+	case InputTypeBool:
+		return false
+	case InputTypeList:
+		return []string{}
+	default:
+		return ""
+	}
+}
+
+func stringInSlice(str string, list []string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
+
+// SetInputs 设置模板输入参数的值, 字符串形式的值将按参数类型解析
+func (p *Parser) SetInputs(inputs map[string]interface{}) *Parser {
+	if p.inputs == nil {
+		p.inputs = make(map[string]interface{}, len(inputs))
+	}
+	for k, v := range inputs {
+		p.inputs[k] = v
+	}
+	return p
+}
+
+// SetInput 设置单个模板输入参数的值
+func (p *Parser) SetInput(name string, value interface{}) *Parser {
+	return p.SetInputs(map[string]interface{}{name: value})
+}
+
+// LoadInputsByFilePath 通过YAML或JSON文件加载模板输入参数, 文件格式为 `参数名称: 值`
+func (p *Parser) LoadInputsByFilePath(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return errors.New("文件打开失败: " + err.Error())
+	}
+
+	node := &yaml.Node{}
+	if err = yaml.Unmarshal(content, node); err != nil {
+		return fmt.Errorf("解析输入参数文件[%s]失败: %w", filePath, err)
+	}
+	res, err := decodeFieldNode(node)
+	if err != nil {
+		return fmt.Errorf("解析输入参数文件[%s]失败: %w", filePath, err)
+	}
+	if res == nil {
+		return nil
+	}
+	inputs, ok := res.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("解析输入参数文件[%s]失败: 内容必须为Map", filePath)
+	}
+
+	p.SetInputs(inputs)
+	return nil
+}
+
+// resolveInputs 按模板声明转换并校验输入参数, 未提供的参数使用默认值或类型零值
+func (p *Parser) resolveInputs(declared []*InputInfo) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(declared))
+	declaredNames := make(map[string]bool, len(declared))
+
+	var problems []string
+	for _, input := range declared {
+		declaredNames[input.Name] = true
+
+		v, ok := p.inputs[input.Name]
+		if !ok || v == nil {
+			v = input.Default
+		}
+
+		if v == nil {
+			if input.Required {
+				problems = append(problems, fmt.Sprintf("[%s]: 缺少必填的输入参数(行: %d)", input.Name, input.line))
+				continue
+			}
+			res[input.Name] = input.zero()
+			continue
+		}
+
+		val, err := input.convert(v)
+		if err == nil {
+			err = input.validate(val)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("[%s]: %s(行: %d)", input.Name, err.Error(), input.line))
+			continue
+		}
+		res[input.Name] = val
+	}
+
+	undeclared := make([]string, 0)
+	for name := range p.inputs {
+		if !declaredNames[name] {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		problems = append(problems, fmt.Sprintf("[%s]: 模板未声明该输入参数", name))
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("输入参数校验失败:\n%s", strings.Join(problems, "\n"))
+	}
+	return res, nil
+}
@@ -0,0 +1,363 @@
+package templateparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// templateErrorPattern 模板语法错误信息, 例: template: refs:2: unexpected "}" in operand
+var templateErrorPattern = regexp.MustCompile(`^template: refs:(\d+)(?::\d+)?: (.*)$`)
+
+// LintLevel 检查结果级别
+type LintLevel string
+
+const (
+	LintLevelError   LintLevel = "error"
+	LintLevelWarning LintLevel = "warning"
+)
+
+// LintKind 检查结果类别
+type LintKind string
+
+const (
+	// LintKindSyntax 模板语法错误
+	LintKindSyntax LintKind = "syntax"
+	// LintKindUndefined 引用了未定义的envs、vars、remoteVars
+	LintKindUndefined LintKind = "undefined"
+	// LintKindUnusedVar 自定义变量(vars)未被引用
+	LintKindUnusedVar LintKind = "unusedVar"
+	// LintKindIgnoredTemplate 模板始终被忽略(ignore: true 且没有配置集取消忽略)
+	LintKindIgnoredTemplate LintKind = "ignoredTemplate"
+)
+
+// LintIssue 模板检查结果
+type LintIssue struct {
+	Level LintLevel `json:"level"`
+	Kind  LintKind  `json:"kind"`
+	// Line 所在行
+	Line int `json:"line"`
+	// Column 所在列
+	Column int `json:"column"`
+	// Path 配置路径, 例: vars.image、templates[src/main.go].content
+	Path string `json:"path"`
+	// Message 描述
+	Message string `json:"message"`
+}
+
+func (i *LintIssue) String() string {
+	return fmt.Sprintf("行: %d, 列: %d, [%s] %s: %s", i.Line, i.Column, i.Level, i.Path, i.Message)
+}
+
+// linter 单个模板文件的检查
+type linter struct {
+	// defined 已定义的envs、vars、remoteVars, 包含导入的模板及配置集中定义的变量
+	defined map[varRef]bool
+	// used 已引用的变量
+	used   map[varRef]bool
+	issues []*LintIssue
+}
+
+// Lint 检查模板文件内容, 报告模板语法错误、引用了未定义的变量、未被引用的自定义变量及始终被忽略的模板.
+// 仅检查当前文件中的配置, 导入的模板用于确定已定义及已引用的变量, 仅识别名称为字符串常量的引用
+func (p *Parser) Lint(content []byte) ([]*LintIssue, error) {
+	return p.lint(content, &importContext{dir: p.WorkerPath})
+}
+
+// LintByFilePath 检查模板文件, 未设置工作路径时本地导入路径相对于模板文件的默认工作路径
+func (p *Parser) LintByFilePath(filePath string) ([]*LintIssue, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.New("文件打开失败: " + err.Error())
+	}
+	return p.lint(content, p.importContextByFilePath(filePath))
+}
+
+func (p *Parser) lint(content []byte, ctx *importContext) ([]*LintIssue, error) {
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(content, root); err != nil {
+		return nil, err
+	}
+
+	info, err := p.parseProjectTemplateInfo(bytes.NewReader(content), ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{
+		defined: make(map[varRef]bool),
+		used:    make(map[varRef]bool),
+	}
+	l.define(info.Envs, info.Vars, info.RemoteVars)
+	l.defineCaptures(info.Executes, info.Templates)
+	for _, profile := range info.Profiles {
+		if profile != nil {
+			l.define(profile.Envs, profile.Vars, profile.RemoteVars)
+			l.defineCaptures(nil, profile.Templates)
+		}
+	}
+	l.useInfo(info)
+
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return l.issues, nil
+	}
+	doc := root.Content[0]
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		switch key.Value {
+		case "envs", "vars", "remoteVars", "executes":
+			l.lintNode(value, key.Value)
+		case "templates":
+			l.lintTemplates(value, key.Value, info)
+		case "profiles":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				l.lintProfile(value.Content[j+1], "profiles."+value.Content[j].Value)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "vars" {
+			l.lintUnusedVars(doc.Content[i+1], "vars")
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		if l.issues[i].Line != l.issues[j].Line {
+			return l.issues[i].Line < l.issues[j].Line
+		}
+		return l.issues[i].Column < l.issues[j].Column
+	})
+	return l.issues, nil
+}
+
+// define 记录已定义的变量
+func (l *linter) define(envs, vars *OrderFieldMap, remoteVars *OrderRemoteVarInfoMap) {
+	for _, item := range []struct {
+		section varSection
+		m       *OrderFieldMap
+	}{{varSectionEnvs, envs}, {varSectionVars, vars}} {
+		if item.m == nil || item.m.m == nil {
+			continue
+		}
+		for _, k := range item.m.Keys() {
+			l.defined[varRef{section: item.section, name: k}] = true
+		}
+	}
+	if remoteVars != nil && remoteVars.m != nil {
+		for _, k := range remoteVars.Keys() {
+			l.defined[varRef{section: varSectionRemoteVars, name: k}] = true
+		}
+	}
+}
+
+// defineCaptures 将命令(executes及模板的afterWrite)通过capture保存的输出记录为已定义的自定义变量
+func (l *linter) defineCaptures(executes *ExecuteInfo, templates map[string]*TemplateFileInfo) {
+	var commands [][]*ExecuteCommand
+	if executes != nil {
+		commands = append(commands, executes.Pre, executes.Post, executes.AfterEnvs, executes.AfterVars, executes.AfterRemoteVars)
+	}
+	for _, template := range templates {
+		if template != nil {
+			commands = append(commands, template.AfterWrite)
+		}
+	}
+
+	for _, list := range commands {
+		for _, command := range list {
+			if command != nil && command.Capture != "" {
+				l.defined[varRef{section: varSectionVars, name: command.Capture}] = true
+			}
+		}
+	}
+}
+
+// useInfo 记录模板(包含导入的模板)中引用的变量
+func (l *linter) useInfo(info *ProjectTemplateInfo) {
+	for _, ref := range valueRefs(info) {
+		l.used[ref] = true
+	}
+
+	useTemplates := func(templates map[string]*TemplateFileInfo) {
+		for k, v := range templates {
+			refs, _ := templateRefs(k)
+			if v != nil && v.Range != "" {
+				rangeRefs, _ := templateRefs(rangeTemplate(v.Range))
+				refs = append(refs, rangeRefs...)
+			}
+			for _, ref := range refs {
+				l.used[ref] = true
+			}
+		}
+	}
+	useTemplates(info.Templates)
+	for _, profile := range info.Profiles {
+		if profile != nil {
+			useTemplates(profile.Templates)
+		}
+	}
+}
+
+// lintProfile 检查配置集
+func (l *linter) lintProfile(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "envs", "vars", "remoteVars":
+			l.lintNode(value, path+"."+key.Value)
+		case "templates":
+			l.lintTemplates(value, path+"."+key.Value, nil)
+		}
+	}
+}
+
+// lintTemplates 检查templates, 模板key同样为模板字符串, info不为空时检查始终被忽略的模板
+func (l *linter) lintTemplates(node *yaml.Node, path string, info *ProjectTemplateInfo) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		templatePath := fmt.Sprintf("%s[%s]", path, key.Value)
+		l.lintString(key.Value, key, templatePath)
+
+		if value.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			field, fieldValue := value.Content[j], value.Content[j+1]
+			fieldPath := templatePath + "." + field.Value
+			switch field.Value {
+			case "range":
+				if fieldValue.Kind == yaml.ScalarNode && strings.TrimSpace(fieldValue.Value) != "" {
+					l.lintString(rangeTemplate(fieldValue.Value), fieldValue, fieldPath)
+				}
+			case "ignore":
+				if info != nil && fieldValue.Value == "true" && !l.enabledByProfile(key.Value, info) {
+					l.add(LintLevelWarning, LintKindIgnoredTemplate, key, templatePath, "模板配置了 ignore: true 且没有配置集取消忽略, 将始终被忽略")
+				}
+			default:
+				l.lintNode(fieldValue, fieldPath)
+			}
+		}
+	}
+}
+
+// enabledByProfile 是否存在配置集取消忽略模板
+func (l *linter) enabledByProfile(key string, info *ProjectTemplateInfo) bool {
+	for _, profile := range info.Profiles {
+		if profile == nil {
+			continue
+		}
+		if v, ok := profile.Templates[key]; ok && v != nil && !v.Ignore {
+			return true
+		}
+	}
+	return false
+}
+
+// lintNode 检查节点中的所有字符串
+func (l *linter) lintNode(node *yaml.Node, path string) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			l.lintNode(node.Content[i+1], path+"."+node.Content[i].Value)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			l.lintNode(item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" {
+			l.lintString(node.Value, node, path)
+		}
+	}
+}
+
+// lintString 检查模板字符串的语法及引用
+func (l *linter) lintString(str string, node *yaml.Node, path string) {
+	refs, err := templateRefs(str)
+	if err != nil {
+		l.addSyntaxError(node, path, err)
+		return
+	}
+
+	for _, ref := range refs {
+		l.used[ref] = true
+		if !l.defined[ref] {
+			l.add(LintLevelError, LintKindUndefined, node, path, fmt.Sprintf("引用了未定义的 %s", ref))
+		}
+	}
+}
+
+// lintUnusedVars 检查未被引用的自定义变量, 支持Map及 `key=value` 列表格式
+func (l *linter) lintUnusedVars(node *yaml.Node, path string) {
+	check := func(name string, keyNode *yaml.Node) {
+		if !l.used[varRef{section: varSectionVars, name: name}] {
+			l.add(LintLevelWarning, LintKindUnusedVar, keyNode, path+"."+name, "自定义变量未被引用")
+		}
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			check(node.Content[i].Value, node.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind == yaml.ScalarNode {
+				check(strings.Split(item.Value, "=")[0], item)
+			}
+		}
+	}
+}
+
+// addSyntaxError 添加模板语法错误, 按错误所在的模板行计算YAML中的行
+func (l *linter) addSyntaxError(node *yaml.Node, path string, err error) {
+	issue := &LintIssue{
+		Level:   LintLevelError,
+		Kind:    LintKindSyntax,
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: "模板语法错误: " + err.Error(),
+	}
+
+	if match := templateErrorPattern.FindStringSubmatch(err.Error()); match != nil {
+		issue.Message = "模板语法错误: " + match[2]
+		if line, _ := strconv.Atoi(match[1]); line > 1 {
+			issue.Line += line - 1
+			issue.Column = 0
+		}
+		// 块标量的内容从下一行开始
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			issue.Line++
+			issue.Column = 0
+		}
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *linter) add(level LintLevel, kind LintKind, node *yaml.Node, path, message string) {
+	l.issues = append(l.issues, &LintIssue{
+		Level:   level,
+		Kind:    kind,
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: message,
+	})
+}
@@ -0,0 +1,308 @@
+package templateparser
+
+import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"strings"
+)
+
+// mavenQualifiers maven版本限定符的顺序, 空字符串表示正式版本, 未知的限定符排在sp之后并按字典序比较
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// mavenReleaseIndex 正式版本在mavenQualifiers中的下标, 之前的限定符均为预发布版本
+const mavenReleaseIndex = 5
+
+// mavenQualifierAliases maven版本限定符别名
+var mavenQualifierAliases = map[string]string{
+	"ga":      "",
+	"final":   "",
+	"release": "",
+	"cr":      "rc",
+}
+
+// mavenVersionItem maven版本号的组成部分, other为nil时与空值(0、正式版本)比较
+type mavenVersionItem interface {
+	compare(other mavenVersionItem) int
+	isNull() bool
+}
+
+// mavenIntItem 数字, 去除前导0后保存, 避免超出整数范围
+type mavenIntItem string
+
+// mavenStringItem 限定符
+type mavenStringItem string
+
+// mavenListItem 以 `-` 或数字与字母交替分隔的子列表
+type mavenListItem []mavenVersionItem
+
+// mavenVersion 按maven ComparableVersion规则比较的版本号, 例: 2.3.0.RELEASE、5.3.20.Final、1.0-SNAPSHOT
+type mavenVersion struct {
+	raw   string
+	items mavenListItem
+}
+
+func newMavenIntItem(str string) mavenIntItem {
+	str = strings.TrimLeft(str, "0")
+	if str == "" {
+		str = "0"
+	}
+	return mavenIntItem(str)
+}
+
+func (i mavenIntItem) isNull() bool {
+	return i == "0"
+}
+
+func (i mavenIntItem) compare(other mavenVersionItem) int {
+	switch o := other.(type) {
+	case nil:
+		if i.isNull() {
+			return 0
+		}
+		return 1
+	case mavenIntItem:
+		if len(i) != len(o) {
+			if len(i) < len(o) {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(string(i), string(o))
+	default:
+		// 数字大于限定符及子列表, 例: 1.1 > 1-sp > 1-alpha
+		return 1
+	}
+}
+
+// newMavenStringItem 创建限定符, 紧跟数字的单个字母a、b、m分别为alpha、beta、milestone的缩写
+func newMavenStringItem(str string, followedByDigit bool) mavenStringItem {
+	if followedByDigit && len(str) == 1 {
+		switch str {
+		case "a":
+			str = "alpha"
+		case "b":
+			str = "beta"
+		case "m":
+			str = "milestone"
+		}
+	}
+	if alias, ok := mavenQualifierAliases[str]; ok {
+		str = alias
+	}
+	return mavenStringItem(str)
+}
+
+// index 限定符的顺序, 未知的限定符返回-1
+func (s mavenStringItem) index() int {
+	for i, q := range mavenQualifiers {
+		if string(s) == q {
+			return i
+		}
+	}
+	return -1
+}
+
+// comparable 可按字典序比较的限定符
+func (s mavenStringItem) comparable() string {
+	if i := s.index(); i != -1 {
+		return fmt.Sprintf("%d", i)
+	}
+	return fmt.Sprintf("%d-%s", len(mavenQualifiers), s)
+}
+
+func (s mavenStringItem) isNull() bool {
+	return s == ""
+}
+
+func (s mavenStringItem) compare(other mavenVersionItem) int {
+	switch o := other.(type) {
+	case nil:
+		return strings.Compare(s.comparable(), mavenStringItem("").comparable())
+	case mavenStringItem:
+		return strings.Compare(s.comparable(), o.comparable())
+	default:
+		return -1
+	}
+}
+
+func (l mavenListItem) isNull() bool {
+	return len(l) == 0
+}
+
+func (l mavenListItem) compare(other mavenVersionItem) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+	case mavenIntItem:
+		return -1
+	case mavenStringItem:
+		return 1
+	case mavenListItem:
+		for i := 0; i < len(l) || i < len(o); i++ {
+			var res int
+			switch {
+			case i >= len(l):
+				res = -o[i].compare(nil)
+			case i >= len(o):
+				res = l[i].compare(nil)
+			default:
+				res = l[i].compare(o[i])
+			}
+			if res != 0 {
+				return res
+			}
+		}
+	}
+	return 0
+}
+
+// normalize 去除末尾的空值, 例: 1.0.0 => 1, 2.3.0.RELEASE => 2.3
+func (l mavenListItem) normalize() mavenListItem {
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].isNull() {
+			l = append(l[:i], l[i+1:]...)
+		} else if _, ok := l[i].(mavenListItem); !ok {
+			break
+		}
+	}
+	return l
+}
+
+// parseMavenVersion 解析maven版本号, `.` 分隔同级部分, `-` 及数字与字母的交替处开始子列表
+func parseMavenVersion(raw string) *mavenVersion {
+	version := strings.ToLower(raw)
+
+	// 子列表在解析完成后才能确定内容, 使用指针保存
+	root := &mavenListItem{}
+	list := root
+	stack := []*mavenListItem{root}
+	newList := func() {
+		sub := &mavenListItem{}
+		stack = append(stack, sub)
+		list = sub
+	}
+	parseItem := func(isDigit bool, str string) mavenVersionItem {
+		if isDigit {
+			return newMavenIntItem(str)
+		}
+		return newMavenStringItem(str, false)
+	}
+
+	isDigit := false
+	start := 0
+	for i := 0; i < len(version); i++ {
+		c := version[i]
+		switch {
+		case c == '.':
+			if i == start {
+				*list = append(*list, newMavenIntItem("0"))
+			} else {
+				*list = append(*list, parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+		case c == '-':
+			if i == start {
+				*list = append(*list, newMavenIntItem("0"))
+			} else {
+				*list = append(*list, parseItem(isDigit, version[start:i]))
+			}
+			start = i + 1
+			newList()
+		case c >= '0' && c <= '9':
+			if !isDigit && i > start {
+				*list = append(*list, newMavenStringItem(version[start:i], true))
+				start = i
+				newList()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				*list = append(*list, parseItem(true, version[start:i]))
+				start = i
+				newList()
+			}
+			isDigit = false
+		}
+	}
+	if len(version) > start {
+		*list = append(*list, parseItem(isDigit, version[start:]))
+	}
+
+	// 由内向外规范化子列表, 并追加至上级列表
+	for i := len(stack) - 1; i > 0; i-- {
+		*stack[i] = stack[i].normalize()
+		*stack[i-1] = append(*stack[i-1], *stack[i])
+	}
+	return &mavenVersion{raw: raw, items: root.normalize()}
+}
+
+// compare 比较版本号, 返回-1、0、1
+func (v *mavenVersion) compare(other *mavenVersion) int {
+	return v.items.compare(other.items)
+}
+
+// flatten 展开子列表
+func (l mavenListItem) flatten() []mavenVersionItem {
+	var res []mavenVersionItem
+	for _, item := range l {
+		if sub, ok := item.(mavenListItem); ok {
+			res = append(res, sub.flatten()...)
+		} else {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// prerelease 是否为预发布版本, 首个限定符为alpha、beta、milestone、rc、snapshot
+func (v *mavenVersion) prerelease() bool {
+	for _, item := range v.items.flatten() {
+		if s, ok := item.(mavenStringItem); ok {
+			i := s.index()
+			return i != -1 && i < mavenReleaseIndex
+		}
+	}
+	return false
+}
+
+// semver 转换为semver版本用于版本约束, 取前三个数字为主版本、次版本、修订号, 预发布限定符及之后的部分为预发布版本,
+// 第四个数字及sp等正式版本之后的限定符无法在semver中表示, 视为与前三个数字相同的正式版本. 无法转换时返回nil
+func (v *mavenVersion) semver() *semver.Version {
+	items := v.items.flatten()
+	numbers := []string{"0", "0", "0"}
+	n := 0
+	for n < len(items) && n < len(numbers) {
+		i, ok := items[n].(mavenIntItem)
+		if !ok {
+			break
+		}
+		numbers[n] = string(i)
+		n++
+	}
+	if n == 0 && len(items) > 0 {
+		return nil
+	}
+
+	str := strings.Join(numbers, ".")
+	if v.prerelease() {
+		rest := make([]string, 0, len(items)-n)
+		for _, item := range items[n:] {
+			switch i := item.(type) {
+			case mavenIntItem:
+				rest = append(rest, string(i))
+			case mavenStringItem:
+				rest = append(rest, string(i))
+			}
+		}
+		str += "-" + strings.Join(rest, ".")
+	}
+
+	version, err := semver.StrictNewVersion(str)
+	if err != nil {
+		return nil
+	}
+	return version
+}
@@ -0,0 +1,124 @@
+package templateparser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// RemoteVarMock 动态变量的模拟响应, 配置后将跳过动态变量的请求
+type RemoteVarMock struct {
+	// ExitCode 响应状态码, 默认: 200
+	ExitCode string `yaml:"exitCode,omitempty"`
+	// ExitMsg 响应状态信息, 默认为状态码对应的http状态信息
+	ExitMsg string `yaml:"exitMsg,omitempty"`
+	// Headers 响应头
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Data 响应数据, 配置后直接作为响应数据使用, 不再经过响应解析器
+	Data interface{} `yaml:"data,omitempty"`
+	// Content 原始响应内容, 未配置data时使用动态变量的responseParser与postResponseParser解析
+	Content string `yaml:"content,omitempty"`
+	// File 原始响应内容所在文件, 相对路径相对于mock文件所在目录
+	File string `yaml:"file,omitempty"`
+}
+
+// remoteVarMocksFile mock文件内容
+type remoteVarMocksFile struct {
+	Mocks map[string]*RemoteVarMock `yaml:"mocks"`
+}
+
+// SetRemoteVarMocks 设置动态变量的模拟响应, key为动态变量名称
+func (p *Parser) SetRemoteVarMocks(mocks map[string]*RemoteVarMock) *Parser {
+	if p.mocks == nil {
+		p.mocks = make(map[string]*RemoteVarMock, len(mocks))
+	}
+	for k, v := range mocks {
+		p.mocks[k] = v
+	}
+	return p
+}
+
+// LoadRemoteVarMocksByFilePath 通过文件加载动态变量的模拟响应, 文件格式为 `mocks: {动态变量名称: 模拟响应}`
+func (p *Parser) LoadRemoteVarMocksByFilePath(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return errors.New("文件打开失败: " + err.Error())
+	}
+
+	mocksFile := &remoteVarMocksFile{}
+	if err = yaml.Unmarshal(content, mocksFile); err != nil {
+		return fmt.Errorf("解析mock文件[%s]失败: %w", filePath, err)
+	}
+
+	dir := filepath.Dir(filePath)
+	for k, v := range mocksFile.Mocks {
+		if v == nil {
+			return fmt.Errorf("mock文件[%s]: 动态变量[%s]的模拟响应为空", filePath, k)
+		}
+		if v.File != "" && !filepath.IsAbs(v.File) {
+			v.File = filepath.Join(dir, v.File)
+		}
+	}
+
+	p.SetRemoteVarMocks(mocksFile.Mocks)
+	return nil
+}
+
+// apply 将模拟响应设置为动态变量的响应
+func (m *RemoteVarMock) apply(p *Parser, varInfo *RemoteVarInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	d := &ResponseInfo{
+		ExitCode: m.ExitCode,
+		ExitMsg:  m.ExitMsg,
+		Headers:  make(http.Header),
+		Metadata: m,
+	}
+	if d.ExitCode == "" {
+		d.ExitCode = "200"
+	}
+	if d.ExitMsg == "" {
+		code, _ := strconv.Atoi(d.ExitCode)
+		d.ExitMsg = fmt.Sprintf("%s %s", d.ExitCode, http.StatusText(code))
+	}
+	for k, v := range m.Headers {
+		d.Headers.Set(k, v)
+	}
+	d.Cookies = (&http.Response{Header: d.Headers}).Cookies()
+
+	if m.Data != nil {
+		d.Data = m.Data
+		varInfo.Response = d
+		return nil
+	}
+
+	var reader io.Reader
+	if m.File != "" {
+		file, err := os.OpenFile(m.File, os.O_RDONLY, 0666)
+		if err != nil {
+			return fmt.Errorf("remoteVars[%s]: 打开mock文件[%s]失败: %w", thisInfo.Name, m.File, err)
+		}
+		defer file.Close()
+		reader = file
+		d.ResponseRawFilePath = m.File
+	} else {
+		resStoreDir := filepath.Join(thisInfo.cacheDirPath, "remoteVars", thisInfo.Name, "mock")
+		_ = os.MkdirAll(resStoreDir, 0777)
+		d.ResponseRawFilePath = filepath.Join(resStoreDir, "_response.raw")
+		if err := os.WriteFile(d.ResponseRawFilePath, []byte(m.Content), 0655); err != nil {
+			return fmt.Errorf("remoteVars[%s]: 保存mock响应内容失败: %w", thisInfo.Name, err)
+		}
+		reader = bytes.NewReader([]byte(m.Content))
+	}
+
+	if err := parseResponseData(p, varInfo, d, reader, data, thisInfo); err != nil {
+		return err
+	}
+
+	varInfo.Response = d
+	return nil
+}
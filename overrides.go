@@ -0,0 +1,230 @@
+package templateparser
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 变量覆盖的优先级(由低到高): 模板(包含导入的模板) < 配置集(profiles) < values文件(按加载顺序) < 环境变量(TPL_VAR_*、TPL_ENV_*) < SetVar/SetEnv(--set)
+const (
+	// EnvVarOverridePrefix 覆盖自定义变量(vars)的环境变量前缀, 例: TPL_VAR_jdkVersion=17
+	EnvVarOverridePrefix = "TPL_VAR_"
+	// EnvEnvOverridePrefix 覆盖环境变量(envs)的环境变量前缀, 例: TPL_ENV_JAVA_HOME=/opt/jdk
+	EnvEnvOverridePrefix = "TPL_ENV_"
+)
+
+// overrideKind 覆盖的目标
+type overrideKind string
+
+const (
+	overrideVars overrideKind = "vars"
+	overrideEnvs overrideKind = "envs"
+)
+
+// valueOverride 单个变量覆盖
+type valueOverride struct {
+	kind  overrideKind
+	key   string
+	value interface{}
+}
+
+// valuesFile values文件内容
+type valuesFile struct {
+	Vars *OrderFieldMap `yaml:"vars,omitempty"`
+	Envs *OrderFieldMap `yaml:"envs,omitempty"`
+}
+
+// ParseOverrideValue 按YAML规则解析覆盖值, 仅解析整数、浮点数、布尔值、null、引号包裹的字符串及 `[...]`、`{...}` 形式的列表与Map,
+// 例: `3` 为整数, `true` 为布尔值, `[a, b]` 为列表, `"1.10"` 为字符串, 其余内容原样作为字符串
+func ParseOverrideValue(value string) interface{} {
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal([]byte(value), node); err != nil || len(node.Content) != 1 {
+		return value
+	}
+
+	content := node.Content[0]
+	switch content.Kind {
+	case yaml.ScalarNode:
+		if content.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			return content.Value
+		}
+		if content.Style != 0 || content.LineComment != "" {
+			return value
+		}
+		switch content.Tag {
+		case "!!int", "!!float", "!!bool", "!!null":
+		default:
+			return value
+		}
+	case yaml.SequenceNode, yaml.MappingNode:
+		if content.Style&yaml.FlowStyle == 0 {
+			return value
+		}
+	default:
+		return value
+	}
+
+	res, err := decodeFieldValue(content)
+	if err != nil {
+		return value
+	}
+	return res
+}
+
+// SetVar 覆盖自定义变量(vars), 优先级最高, key支持使用 `.` 设置Map变量中的值, 例: db.host
+func (p *Parser) SetVar(key string, value interface{}) *Parser {
+	p.setOverrides = append(p.setOverrides, &valueOverride{kind: overrideVars, key: key, value: value})
+	return p
+}
+
+// SetEnv 覆盖环境变量(envs), 优先级最高
+func (p *Parser) SetEnv(key string, value string) *Parser {
+	p.setOverrides = append(p.setOverrides, &valueOverride{kind: overrideEnvs, key: key, value: value})
+	return p
+}
+
+// SetVarByString 通过 `key=value` 格式覆盖自定义变量, value按YAML规则解析
+func (p *Parser) SetVarByString(keyValue string) error {
+	kv := strings.SplitN(keyValue, "=", 2)
+	if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+		return fmt.Errorf("错误的变量覆盖[%s], 格式应为: key=value", keyValue)
+	}
+	p.SetVar(strings.TrimSpace(kv[0]), ParseOverrideValue(kv[1]))
+	return nil
+}
+
+// LoadValuesByFilePath 加载values文件, 文件格式为 `vars: {...}` 与 `envs: {...}`, 多个文件按加载顺序覆盖
+func (p *Parser) LoadValuesByFilePath(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return errors.New("文件打开失败: " + err.Error())
+	}
+
+	values := &valuesFile{}
+	if err = yaml.Unmarshal(content, values); err != nil {
+		return fmt.Errorf("解析values文件[%s]失败: %w", filePath, err)
+	}
+
+	for _, item := range []struct {
+		kind overrideKind
+		m    *OrderFieldMap
+	}{{overrideVars, values.Vars}, {overrideEnvs, values.Envs}} {
+		if item.m == nil || item.m.m == nil {
+			continue
+		}
+		for _, k := range item.m.Keys() {
+			v, _ := item.m.Get(k)
+			p.valuesOverrides = append(p.valuesOverrides, &valueOverride{kind: item.kind, key: k, value: v})
+		}
+	}
+	return nil
+}
+
+// UseEnvironmentOverrides 启用后, 使用 TPL_VAR_* 与 TPL_ENV_* 环境变量覆盖模板中的vars与envs.
+// TPL_VAR_ 之后的名称忽略大小写与下划线匹配已存在的变量, 例: TPL_VAR_JDK_VERSION 覆盖 jdkVersion
+func (p *Parser) UseEnvironmentOverrides(enable bool) *Parser {
+	p.envOverrides = enable
+	return p
+}
+
+// environmentOverrides 读取环境变量中的覆盖配置, 按名称排序
+func (p *Parser) environmentOverrides() []*valueOverride {
+	var res []*valueOverride
+	environ := os.Environ()
+	sort.Strings(environ)
+	for _, kv := range environ {
+		kvSplit := strings.SplitN(kv, "=", 2)
+		if len(kvSplit) != 2 {
+			continue
+		}
+
+		name, value := kvSplit[0], kvSplit[1]
+		if key := strings.TrimPrefix(name, EnvVarOverridePrefix); key != name && key != "" {
+			res = append(res, &valueOverride{kind: overrideVars, key: p.matchVarKey(key), value: ParseOverrideValue(value)})
+		} else if key = strings.TrimPrefix(name, EnvEnvOverridePrefix); key != name && key != "" {
+			res = append(res, &valueOverride{kind: overrideEnvs, key: key, value: value})
+		}
+	}
+	return res
+}
+
+// matchVarKey 忽略大小写与下划线匹配已存在的变量名称, 未匹配时原样返回
+func (p *Parser) matchVarKey(key string) string {
+	if p.TemplateInfo == nil || p.TemplateInfo.Vars == nil || p.TemplateInfo.Vars.m == nil {
+		return key
+	}
+
+	if _, ok := p.TemplateInfo.Vars.m.Get(key); ok {
+		return key
+	}
+
+	normalize := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "_", ""))
+	}
+	normalized := normalize(key)
+	for _, k := range p.TemplateInfo.Vars.Keys() {
+		if normalize(k) == normalized {
+			return k
+		}
+	}
+	return key
+}
+
+// applyOverrides 按优先级将覆盖配置合并至模板的vars与envs
+func (p *Parser) applyOverrides() {
+	overrides := append([]*valueOverride{}, p.valuesOverrides...)
+	if p.envOverrides {
+		overrides = append(overrides, p.environmentOverrides()...)
+	}
+	overrides = append(overrides, p.setOverrides...)
+	if len(overrides) == 0 {
+		return
+	}
+
+	for _, override := range overrides {
+		target := &p.TemplateInfo.Vars
+		if override.kind == overrideEnvs {
+			target = &p.TemplateInfo.Envs
+		}
+		if *target == nil || (*target).m == nil {
+			*target = NewOrderFieldMap()
+		}
+
+		p.Log("overrides", "%s.%s => %s", override.kind, override.key, stringifyFieldValue(override.value))
+		if override.kind == overrideVars {
+			setVarByPath(*target, override.key, override.value)
+			continue
+		}
+		(*target).Set(override.key, stringifyFieldValue(override.value))
+	}
+}
+
+// setVarByPath 设置变量, key中存在 `.` 且不存在同名变量时设置Map变量中的值
+func setVarByPath(vars *OrderFieldMap, key string, value interface{}) {
+	if _, ok := vars.m.Get(key); ok || !strings.Contains(key, ".") {
+		vars.Set(key, value)
+		return
+	}
+
+	path := strings.Split(key, ".")
+	root, _ := vars.m.Get(path[0])
+	m, ok := root.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+		vars.Set(path[0], m)
+	}
+
+	for _, k := range path[1 : len(path)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[k] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
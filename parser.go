@@ -22,6 +22,39 @@ type Parser struct {
 	bufferWriter *bufio.Writer
 	logBlockName string
 	breakLog     bool
+
+	// sharedCookieJar 未配置会话的动态变量共享同一cookie jar
+	sharedCookieJar bool
+	// cookieJars 单次解析中的会话cookie
+	cookieJars map[string]http.CookieJar
+	// proxy 代理配置
+	proxy *ProxyConfig
+	// transports 按代理配置复用的transport
+	transports map[string]http.RoundTripper
+	// fixtureMode 动态变量请求录制/回放模式
+	fixtureMode FixtureMode
+	// fixtureDirPath 录制目录
+	fixtureDirPath string
+	// mocks 动态变量的模拟响应
+	mocks map[string]*RemoteVarMock
+	// profiles 启用的配置集
+	profiles []string
+	// valuesOverrides values文件中的变量覆盖
+	valuesOverrides []*valueOverride
+	// envOverrides 使用TPL_VAR_*、TPL_ENV_*环境变量覆盖变量
+	envOverrides bool
+	// setOverrides SetVar/SetEnv设置的变量覆盖
+	setOverrides []*valueOverride
+	// inputs 模板输入参数的值
+	inputs map[string]interface{}
+	// executePolicy 命令执行策略
+	executePolicy *ExecutePolicy
+	// execEventHandler 命令执行事件处理函数
+	execEventHandler ExecEventHandler
+	// logFullExecuteEnv 日志中输出命令执行时的完整环境变量
+	logFullExecuteEnv bool
+	// strict 严格模式
+	strict bool
 }
 
 func NewParserByWorkPath(workerPath string) (*Parser, error) {
@@ -55,11 +88,20 @@ func (p *Parser) Log(blockName, str string, data ...any) *Parser {
 		return p
 	}
 	p.logBlockName = blockName
+	return p.logTo(p.bufferWriter, blockName, str, data...)
+}
+
+// logTo 将日志写入指定的writer
+func (p *Parser) logTo(w *bufio.Writer, blockName, str string, data ...any) *Parser {
+	// 未通过NewParserByWorkPath创建且未设置输出时不输出日志
+	if p.breakLog || w == nil {
+		return p
+	}
 	if !strings.HasSuffix(str, "\n") {
 		str += "\n"
 	}
-	_, _ = p.bufferWriter.WriteString(fmt.Sprintf("["+blockName+"] -> "+str, data...))
-	_ = p.bufferWriter.Flush()
+	_, _ = w.WriteString(fmt.Sprintf("["+blockName+"] -> "+str, data...))
+	_ = w.Flush()
 	return p
 }
 
@@ -77,21 +119,74 @@ func (p *Parser) SetOutput(output io.Writer) *Parser {
 	return p
 }
 
+// UseStrictMode 启用严格模式, 模板中获取Map中不存在的键、获取未定义的envs、vars、remoteVars时报错,
+// 模板文件中配置 `strict: true` 同样启用
+func (p *Parser) UseStrictMode(enable bool) *Parser {
+	p.strict = enable
+	return p
+}
+
+// UseSharedCookieJar 启用后, 单次解析中未配置session的动态变量共享同一cookie jar
+func (p *Parser) UseSharedCookieJar(enable bool) *Parser {
+	p.sharedCookieJar = enable
+	return p
+}
+
+// DefaultWorkPath 模板文件的默认工作路径, 为模板文件所在目录的out目录
+func DefaultWorkPath(templateFilePath string) string {
+	if absPath, err := filepath.Abs(templateFilePath); err == nil {
+		templateFilePath = absPath
+	}
+	return filepath.Join(filepath.Dir(templateFilePath), "out")
+}
+
+// importContext 解析导入的模板时使用的配置
+type importContext struct {
+	// dir 本地导入路径的相对目录
+	dir string
+	// proxy 导入远程模板使用的代理, 为首个配置了代理的模板(通常为根模板)中的代理配置, 优先级低于Parser中的代理
+	proxy *ProxyConfig
+}
+
+// importContextByFilePath 仅读取模板文件不解析时使用, 未设置工作路径时本地导入路径相对于模板文件的默认工作路径
+func (p *Parser) importContextByFilePath(filePath string) *importContext {
+	if p.WorkerPath != "" {
+		return &importContext{dir: p.WorkerPath}
+	}
+	return &importContext{dir: DefaultWorkPath(filePath)}
+}
+
 func (p *Parser) ParseProjectTemplateInfo(content []byte) (*ProjectTemplateInfo, error) {
 	return p.ParseProjectTemplateInfoByReader(bytes.NewReader(content))
 }
 
 func (p *Parser) ParseProjectTemplateInfoByFilePath(filePath string) (*ProjectTemplateInfo, error) {
+	return p.parseProjectTemplateInfoByFilePath(filePath, &importContext{dir: p.WorkerPath})
+}
+
+func (p *Parser) parseProjectTemplateInfoByFilePath(filePath string, ctx *importContext) (*ProjectTemplateInfo, error) {
 	file, err := os.OpenFile(filePath, os.O_RDONLY, 0666)
 	if err != nil {
 		return nil, errors.New("文件打开失败: " + err.Error())
 	}
 	defer file.Close()
-	return p.ParseProjectTemplateInfoByReader(file)
+
+	result, err := p.parseProjectTemplateInfo(file, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		result.fillRemoteVarBaseDir(filepath.Dir(absPath))
+	}
+	return result, nil
 }
 
 func (p *Parser) ParseProjectTemplateInfoByReader(reader io.Reader) (*ProjectTemplateInfo, error) {
+	return p.parseProjectTemplateInfo(reader, &importContext{dir: p.WorkerPath})
+}
 
+func (p *Parser) parseProjectTemplateInfo(reader io.Reader, ctx *importContext) (*ProjectTemplateInfo, error) {
 	decoder := yaml.NewDecoder(reader)
 
 	result := &ProjectTemplateInfo{}
@@ -100,15 +195,22 @@ func (p *Parser) ParseProjectTemplateInfoByReader(reader io.Reader) (*ProjectTem
 	}
 
 	if len(result.Import) != 0 {
+		if ctx.proxy == nil && result.Proxy != nil {
+			ctx = &importContext{dir: ctx.dir, proxy: result.Proxy}
+		}
+
 		importTemplateInfo := &ProjectTemplateInfo{}
 		for _, str := range result.Import {
-			if err := p.parserImport(importTemplateInfo, str); err != nil {
+			if err := p.parserImport(importTemplateInfo, str, ctx); err != nil {
 				return nil, err
 			}
 		}
 
 		importTemplateInfo.Shell = result.Shell
 		importTemplateInfo.Executes = result.Executes
+		if result.Proxy != nil {
+			importTemplateInfo.Proxy = result.Proxy
+		}
 		p.mergeProjectTemplateInfo(importTemplateInfo, result)
 		return importTemplateInfo, nil
 	}
@@ -117,43 +219,79 @@ func (p *Parser) ParseProjectTemplateInfoByReader(reader io.Reader) (*ProjectTem
 }
 
 func (p *Parser) mergeProjectTemplateInfo(dest *ProjectTemplateInfo, src *ProjectTemplateInfo) {
-	if dest.Envs == nil {
-		dest.Envs = src.Envs
-	} else if src.Envs != nil && src.Envs.m != nil {
-		for _, key := range src.Envs.Keys() {
-			v, _ := src.Envs.Get(key)
-			dest.Envs.Set(key, v)
+	// 合并至新的Map, 不与src共享, 避免之后的合并及覆盖修改src(例如配置集)中的定义
+	mergeFieldMap := func(dest, src *OrderFieldMap) *OrderFieldMap {
+		if src == nil || src.m == nil {
+			return dest
 		}
-	}
-
-	if dest.Vars == nil {
-		dest.Vars = src.Vars
-	} else if src.Vars != nil && src.Vars.m != nil {
-		for _, key := range src.Vars.Keys() {
-			v, _ := src.Vars.Get(key)
-			dest.Vars.Set(key, v)
+		if dest == nil || dest.m == nil {
+			dest = NewOrderFieldMap()
 		}
+		for _, key := range src.Keys() {
+			v, _ := src.Get(key)
+			dest.Set(key, v)
+			dest.copyPosition(src, key)
+		}
+		return dest
 	}
+	dest.Envs = mergeFieldMap(dest.Envs, src.Envs)
+	dest.Vars = mergeFieldMap(dest.Vars, src.Vars)
 
-	if dest.RemoteVars == nil {
-		dest.RemoteVars = src.RemoteVars
-	} else if src.RemoteVars != nil && src.RemoteVars.m != nil {
+	if src.RemoteVars != nil && src.RemoteVars.m != nil {
+		if dest.RemoteVars == nil || dest.RemoteVars.m == nil {
+			dest.RemoteVars = NewOrderRemoteVarInfoMap()
+		}
 		for _, key := range src.RemoteVars.Keys() {
 			v, _ := src.RemoteVars.Get(key)
 			dest.RemoteVars.Set(key, v)
 		}
 	}
 
-	if len(dest.Templates) == 0 {
-		dest.Templates = src.Templates
-	} else {
-		for k := range src.Templates {
-			dest.Templates[k] = src.Templates[k]
+	if src.Proxy != nil {
+		dest.Proxy = src.Proxy
+	}
+
+	if src.Strict {
+		dest.Strict = true
+	}
+
+	for name, profile := range src.Profiles {
+		if dest.Profiles == nil {
+			dest.Profiles = make(map[string]*ProfileInfo, len(src.Profiles))
 		}
+		dest.Profiles[name] = profile
+	}
+
+	dest.Inputs = mergeByName(dest.Inputs, src.Inputs, func(input *InputInfo) string { return input.Name })
+	dest.Requires = mergeByName(dest.Requires, src.Requires, func(require *RequireInfo) string { return require.Name })
+
+	if dest.Templates == nil && len(src.Templates) > 0 {
+		dest.Templates = make(map[string]*TemplateFileInfo, len(src.Templates))
+	}
+	for k := range src.Templates {
+		dest.Templates[k] = src.Templates[k]
 	}
 }
 
-func (p *Parser) parserImport(prevTemplateInfo *ProjectTemplateInfo, currentTemplatePath string) error {
+// mergeByName 按名称合并列表, 同名项被替换, 其余追加至末尾
+func mergeByName[T any](dest, src []T, name func(T) string) []T {
+	for _, item := range src {
+		replaced := false
+		for i := range dest {
+			if name(dest[i]) == name(item) {
+				dest[i] = item
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dest = append(dest, item)
+		}
+	}
+	return dest
+}
+
+func (p *Parser) parserImport(prevTemplateInfo *ProjectTemplateInfo, currentTemplatePath string, ctx *importContext) error {
 	var (
 		err error
 
@@ -161,19 +299,25 @@ func (p *Parser) parserImport(prevTemplateInfo *ProjectTemplateInfo, currentTemp
 	)
 	if strings.HasPrefix(currentTemplatePath, "http://") || strings.HasPrefix(currentTemplatePath, "https://") {
 		p.Log("import", currentTemplatePath)
-		var resp *http.Response
-		if resp, err = globalSkipVerifyCertHttpClient.Get(currentTemplatePath); err != nil {
+		var (
+			resp   *http.Response
+			client *http.Client
+		)
+		if client, err = p.importHttpClient(ctx); err != nil {
+			return err
+		}
+		if resp, err = client.Get(currentTemplatePath); err != nil {
 			return err
 		}
 		defer resp.Body.Close()
 
-		if currentTemplateInfo, err = p.ParseProjectTemplateInfoByReader(resp.Body); err != nil {
+		if currentTemplateInfo, err = p.parseProjectTemplateInfo(resp.Body, ctx); err != nil {
 			return err
 		}
 	} else {
-		currentTemplatePath = filepath.Join(p.WorkerPath, currentTemplatePath)
+		currentTemplatePath = filepath.Join(ctx.dir, currentTemplatePath)
 		p.Log("import", currentTemplatePath)
-		if currentTemplateInfo, err = p.ParseProjectTemplateInfoByFilePath(currentTemplatePath); err != nil {
+		if currentTemplateInfo, err = p.parseProjectTemplateInfoByFilePath(currentTemplatePath, ctx); err != nil {
 			return err
 		}
 	}
@@ -190,13 +334,12 @@ func (p *Parser) Decode(content []byte, projectInfo *ProjectInfo) error {
 
 // DecodeByFilePath 解析通过文件路径
 func (p *Parser) DecodeByFilePath(filePath string, projectInfo *ProjectInfo) error {
-	file, err := os.OpenFile(filePath, os.O_RDONLY, 0666)
+	projectTemplateInfo, err := p.ParseProjectTemplateInfoByFilePath(filePath)
 	if err != nil {
-		return errors.New("文件打开失败: " + err.Error())
+		return err
 	}
-	defer file.Close()
 
-	return p.DecodeByReader(file, projectInfo)
+	return p.DecodeByProjectTemplateInfo(projectTemplateInfo, projectInfo)
 }
 
 // DecodeByReader 解析通过reader
@@ -226,49 +369,57 @@ func (p *Parser) parseProjectInfo(projectInfo *ProjectInfo) error {
 	projectInfo = settingProjectInfo(projectInfo)
 	cacheDirPath := filepath.Join(p.WorkerPath, ".__temp__.")
 	_ = os.MkdirAll(cacheDirPath, 0777)
+	p.cookieJars = make(map[string]http.CookieJar)
 	defer func() {
 		_ = os.RemoveAll(cacheDirPath)
+		p.cookieJars = nil
 	}()
 	thisInfo := &ThisInfo{
 		templateData: p.TemplateInfo,
 		projectInfo:  projectInfo,
 		cacheDirPath: cacheDirPath,
 	}
-	passData := make(map[string]interface{})
-	passData["Project"] = projectInfo
-	passData["top"] = p.TemplateInfo
-	passData["this"] = thisInfo
-	//region 解析env
-	logs.Debugln("正在解析环境变量(envs)...")
-	p.SetLogBlockName("envs")
-	thisInfo.Type = ThisTypeEnvs
-	if err := p.parseOrderFieldMap(p.TemplateInfo.Envs, passData, thisInfo, nil, nil); err != nil {
+	if err := p.applyProfiles(); err != nil {
 		return err
 	}
-	//endregion
+	p.applyOverrides()
+	thisInfo.strict = p.strict || p.TemplateInfo.Strict
 
-	//region 解析var
-	logs.Debugln("正在解析自定义变量(vars)...")
-	p.SetLogBlockName("vars")
-	thisInfo.Type = ThisTypeVars
-	if err := p.parseOrderFieldMap(p.TemplateInfo.Vars, passData, thisInfo, nil, nil); err != nil {
+	logs.Debugln("正在校验输入参数(inputs)...")
+	inputs, err := p.resolveInputs(p.TemplateInfo.Inputs)
+	if err != nil {
 		return err
 	}
+
+	passData := make(map[string]interface{})
+	passData["Inputs"] = inputs
+	passData["Project"] = projectInfo
+	passData["top"] = p.TemplateInfo
+	passData["this"] = thisInfo
+	executes := p.TemplateInfo.Executes
+	if executes == nil {
+		executes = &ExecuteInfo{}
+	}
+
+	//region 检查所需的可执行文件, 在变量解析及其中的afterEnvs等命令执行之前
+	if len(p.TemplateInfo.Requires) > 0 {
+		logs.Debugln("正在检查所需的可执行文件(requires)...")
+		thisInfo.Type = ThisTypeRequires
+		thisInfo.Name = string(ThisTypeRequires)
+		p.SetLogBlockName("requires")
+		if err := p.checkRequires(p.TemplateInfo.Requires, passData, thisInfo); err != nil {
+			return err
+		}
+	}
 	//endregion
 
-	//region 解析动态变量
-	logs.Debugln("正在解析远程变量(remoteVars)...")
-	p.SetLogBlockName("remoteVars")
-	thisInfo.Type = ThisTypeRemoteVars
-	if err := p.parseOrderRemoteVarInfoMap(passData, thisInfo); err != nil {
+	//region 按依赖顺序解析envs、vars、remoteVars
+	if err := p.parseVars(executes, passData, thisInfo); err != nil {
 		return err
 	}
 	//endregion
 
-	shell := DefaultShellConfig.current
-	if p.TemplateInfo.Shell.current != "" {
-		shell = p.TemplateInfo.Shell.current
-	}
+	shell := p.shellConfig()
 
 	//region 全局pre命令执行器
 
@@ -320,10 +471,9 @@ func (p *Parser) parserTemplate(templates map[string]*TemplateFileInfo, data map
 		}
 		thisInfo.Data = v
 		if v.Range != "" {
-			v.Range = strings.TrimSpace(v.Range)
-			v.Range = `{{ pathRange .this (` + v.Range + ") }}"
+			v.Range = rangeTemplate(v.Range)
 			if _, _, err := getStrByTemplate(v.Range, data, thisInfo); err != nil {
-				return err
+				return v.wrapError(k, err)
 			}
 		}
 
@@ -331,13 +481,13 @@ func (p *Parser) parserTemplate(templates map[string]*TemplateFileInfo, data map
 		thisInfo.pathRange = nil
 		if pathRange != nil {
 			if err := rangeInterface(pathRange, data, func() error {
-				return p.writeTemplateContentToTemplateFile(k, v, data, thisInfo)
+				return p.writeTemplate(k, v, data, thisInfo)
 			}, 0); err != nil {
 				return err
 			}
 			continue
 		}
-		if err := p.writeTemplateContentToTemplateFile(k, v, data, thisInfo); err != nil {
+		if err := p.writeTemplate(k, v, data, thisInfo); err != nil {
 			return err
 		}
 	}
@@ -345,12 +495,54 @@ func (p *Parser) parserTemplate(templates map[string]*TemplateFileInfo, data map
 	return nil
 }
 
-func (p *Parser) writeTemplateContentToTemplateFile(pathTemplate string, fileTemplateInfo *TemplateFileInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+// writeTemplate 写入模板文件并执行模板的afterWrite命令
+func (p *Parser) writeTemplate(pathTemplate string, fileTemplateInfo *TemplateFileInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	filePath, err := p.writeTemplateContentToTemplateFile(pathTemplate, fileTemplateInfo, data, thisInfo)
+	if err != nil {
+		return fileTemplateInfo.wrapError(pathTemplate, err)
+	}
+
+	if len(fileTemplateInfo.AfterWrite) == 0 {
+		return nil
+	}
+
+	relPath, err := filepath.Rel(p.WorkerPath, filePath)
+	if err != nil {
+		relPath = filePath
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		absPath = filePath
+	}
+
+	data["path"] = relPath
+	data["absPath"] = absPath
+	data["key"] = pathTemplate
+	defer func() {
+		delete(data, "path")
+		delete(data, "absPath")
+		delete(data, "key")
+	}()
+
+	prevName := thisInfo.Name
+	defer func() {
+		thisInfo.Type = ThisTypeTemplates
+		thisInfo.Name = prevName
+		thisInfo.Data = fileTemplateInfo
+	}()
+
+	thisInfo.Type = ThisTypeExecuteAfterWrite
+	thisInfo.Name = pathTemplate
+	p.LogWithPrevBlockName("${%s} => afterWrite: %s", pathTemplate, relPath)
+	return p.execCommands(fileTemplateInfo.AfterWrite, p.shellConfig(), data, thisInfo)
+}
+
+func (p *Parser) writeTemplateContentToTemplateFile(pathTemplate string, fileTemplateInfo *TemplateFileInfo, data map[string]interface{}, thisInfo *ThisInfo) (string, error) {
 	defer thisInfo.clearWriteData()
 
 	pr, _, err := getStrByTemplate(pathTemplate, data, thisInfo)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	//pr = p.WorkerPath + "/" + pr
@@ -359,60 +551,60 @@ func (p *Parser) writeTemplateContentToTemplateFile(pathTemplate string, fileTem
 	filePath, _, err := getStrByTemplate(filepath.Join(prSplit...), data, thisInfo)
 	filePath = filepath.Join(p.WorkerPath, filePath)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if fileTemplateInfo.IsDir {
 		p.LogWithPrevBlockName("${%s} => create dir: %s", pathTemplate, filePath)
 		if err = os.MkdirAll(filePath, 0777); err != nil {
-			return errors.New(fmt.Sprintf("创建目录[%s]失败: %s", filePath, err.Error()))
+			return "", errors.New(fmt.Sprintf("创建目录[%s]失败: %s", filePath, err.Error()))
 		}
-		return nil
+		return filePath, nil
 	}
 	_ = os.MkdirAll(filepath.Dir(filePath), 0777)
 
 	if fileTemplateInfo.Content == "" && fileTemplateInfo.Path == "" {
-		return fmt.Errorf("文件[%s]缺失内容描述", filePath)
+		return "", fmt.Errorf("文件[%s]缺失内容描述", filePath)
 	}
 
 	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE, 0666)
 	if err != nil {
-		return errors.New(fmt.Sprintf("打开文件[%s]失败: %s", filePath, err.Error()))
+		return "", errors.New(fmt.Sprintf("打开文件[%s]失败: %s", filePath, err.Error()))
 	}
 	defer file.Close()
 
 	if fileTemplateInfo.Path != "" {
-		filePath, _, err = getStrByTemplate(fileTemplateInfo.Path, data, thisInfo)
+		srcPath, _, err := getStrByTemplate(fileTemplateInfo.Path, data, thisInfo)
 		if err != nil {
-			return err
+			return "", err
 		}
 
-		f, err := os.OpenFile(filePath, os.O_RDONLY, 0655)
+		f, err := os.OpenFile(srcPath, os.O_RDONLY, 0655)
 		if err != nil {
-			return err
+			return "", err
 		}
 		defer f.Close()
 
 		if _, err = io.Copy(file, f); err != nil {
-			return err
+			return "", err
 		}
 
 		thisInfo.writeFileList = nil
-		p.LogWithPrevBlockName("${%s} => copy file: %s", pathTemplate, filePath)
-		return nil
+		p.LogWithPrevBlockName("${%s} => copy file: %s", pathTemplate, srcPath)
+		return filePath, nil
 	}
 
 	cr, _, err := getBytesByTemplate(fileTemplateInfo.Content, data, thisInfo)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if len(thisInfo.writeFileList) == 0 {
 		if _, err = file.Write(cr); err != nil {
-			return errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
+			return "", errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
 		}
 		p.LogWithPrevBlockName("${%s} => write content to: %s", pathTemplate, filePath)
-		return nil
+		return filePath, nil
 	}
 
 	for {
@@ -423,15 +615,15 @@ func (p *Parser) writeTemplateContentToTemplateFile(pathTemplate string, fileTem
 
 		index := bytes.Index(cr, writeSplitBytes)
 		if index == -1 {
-			return errors.New("表达式与预期不否，已查找到二进制数据，但未识别标识符")
+			return "", errors.New("表达式与预期不否，已查找到二进制数据，但未识别标识符")
 		}
 
 		if _, err = file.Write(cr[:index]); err != nil {
-			return errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
+			return "", errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
 		}
 
 		if err = thisInfo.writeData(file); err != nil {
-			return errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
+			return "", errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
 		}
 
 		cr = cr[index+writeSplitLen:]
@@ -439,43 +631,124 @@ func (p *Parser) writeTemplateContentToTemplateFile(pathTemplate string, fileTem
 
 	if len(cr) > 0 {
 		if _, err = file.Write(cr); err != nil {
-			return errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
+			return "", errors.New(fmt.Sprintf("向文件[%s]写入内容失败: %s", filePath, err.Error()))
 		}
 	}
 	p.LogWithPrevBlockName("${%s} => write content and bytes data to: %s", pathTemplate, filePath)
-	return nil
+	return filePath, nil
 }
 
-// parseOrderRemoteVarInfoMap 解析动态变量
-func (p *Parser) parseOrderRemoteVarInfoMap(data map[string]interface{}, thisInfo *ThisInfo) (err error) {
-	remoteVars := p.TemplateInfo.RemoteVars
-	if remoteVars == nil {
+// parseVars 按模板中的引用关系解析envs、vars、remoteVars, 被引用的变量先于引用方解析,
+// 各配置段的变量全部解析完成后执行对应的afterEnvs、afterVars、afterRemoteVars命令
+func (p *Parser) parseVars(executes *ExecuteInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	logs.Debugln("正在解析变量(envs、vars、remoteVars)...")
+	graph := buildVarGraph(p.TemplateInfo)
+	nodes, err := graph.sort()
+	if err != nil {
+		return err
+	}
+	if thisInfo.strict {
+		if err = graph.undefinedError(); err != nil {
+			return err
+		}
+	}
+
+	sections := []struct {
+		section  varSection
+		thisType ThisType
+		hookType ThisType
+		hook     []*ExecuteCommand
+	}{
+		{varSectionEnvs, ThisTypeEnvs, ThisTypeExecuteAfterEnvs, executes.AfterEnvs},
+		{varSectionVars, ThisTypeVars, ThisTypeExecuteAfterVars, executes.AfterVars},
+		{varSectionRemoteVars, ThisTypeRemoteVars, ThisTypeExecuteAfterRemoteVars, executes.AfterRemoteVars},
+	}
+	remaining := make(map[varSection]int, len(sections))
+	for _, node := range nodes {
+		remaining[node.section]++
+	}
+
+	// 配置段中的变量均已解析时执行对应的命令, 空配置段在解析前执行
+	fired := make(map[varSection]bool, len(sections))
+	fireHooks := func() error {
+		for _, item := range sections {
+			if fired[item.section] || remaining[item.section] > 0 {
+				continue
+			}
+			fired[item.section] = true
+			if err := p.execStageHook(item.hookType, item.hook, data, thisInfo); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
-	keys := remoteVars.Keys()
-	if len(keys) == 0 {
-		return
+	if err = fireHooks(); err != nil {
+		return err
 	}
 
-	for _, k := range keys {
-		thisInfo.Name = k
-		val, _ := remoteVars.Get(k)
-		if err = val.Parse(data, thisInfo, p); err != nil {
-			return
+	for _, node := range nodes {
+		i := graph.index[node.varRef]
+		p.SetLogBlockName(string(node.section))
+		for _, ref := range graph.undefined[i] {
+			p.LogWithPrevBlockName("警告: %s 引用了未定义的 %s", node, ref)
 		}
-		if err = val.Req.Do(p); err != nil {
-			return
+		for _, ref := range graph.forwardRefs(i) {
+			p.LogWithPrevBlockName("%s 引用了其后声明的 %s, 已优先解析 %s", node, ref, ref)
+		}
+
+		switch node.section {
+		case varSectionEnvs:
+			thisInfo.Type = ThisTypeEnvs
+			err = p.parseOrderFieldItem(p.TemplateInfo.Envs, node.name, data, thisInfo, nil, nil)
+		case varSectionVars:
+			thisInfo.Type = ThisTypeVars
+			err = p.parseOrderFieldItem(p.TemplateInfo.Vars, node.name, data, thisInfo, nil, nil)
+		case varSectionRemoteVars:
+			thisInfo.Type = ThisTypeRemoteVars
+			err = p.parseRemoteVar(p.TemplateInfo.RemoteVars, node.name, data, thisInfo)
+		}
+		if err != nil {
+			return node.wrapError(err)
 		}
 
-		if marshal, err := json.Marshal(val.Response.Data); err != nil {
-			p.LogWithPrevBlockName("${%s}: result data => %#v", k, val.Response.Data)
-		} else {
-			p.LogWithPrevBlockName("${%s}: result data => %s", k, marshal)
+		remaining[node.section]--
+		if err = fireHooks(); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// parseRemoteVar 解析单个动态变量
+func (p *Parser) parseRemoteVar(remoteVars *OrderRemoteVarInfoMap, k string, data map[string]interface{}, thisInfo *ThisInfo) (err error) {
+	thisInfo.Name = k
+	val, _ := remoteVars.Get(k)
+	if mock, ok := p.mocks[k]; ok {
+		if val == nil {
+			val = &RemoteVarParser{RemoteVarInfo: &RemoteVarInfo{}}
+			remoteVars.Set(k, val)
+		}
+		thisInfo.Data = val.RemoteVarInfo
+		if err = mock.apply(p, val.RemoteVarInfo, data, thisInfo); err != nil {
+			return
+		}
+		p.LogWithPrevBlockName("${%s}: use mock response", k)
+	} else if val == nil {
+		return fmt.Errorf("remoteVars[%s]: 配置为空", k)
+	} else if err = val.Parse(data, thisInfo, p); err != nil {
+		return fmt.Errorf("remoteVars[%s]: %w", k, err)
+	} else if err = val.Req.Do(p); err != nil {
+		return
+	}
+
+	if marshal, err := json.Marshal(val.Response.Data); err != nil {
+		p.LogWithPrevBlockName("${%s}: result data => %#v", k, val.Response.Data)
+	} else {
+		p.LogWithPrevBlockName("${%s}: result data => %s", k, marshal)
+	}
+	return nil
+}
+
 // parseOrderFieldMap 解析排序Map
 func (p *Parser) parseOrderFieldMap(fieldMap *OrderFieldMap, data map[string]interface{}, thisInfo *ThisInfo, callBakWithStrFn func(k string, v string) error, callBackWithStrSliceFn func(k string, v []string) error) (err error) {
 	if fieldMap == nil {
@@ -486,41 +759,125 @@ func (p *Parser) parseOrderFieldMap(fieldMap *OrderFieldMap, data map[string]int
 		return nil
 	}
 
+	// 解析时thisInfo.Name为当前键, 完成后恢复, 避免动态变量的名称被请求头等配置的键覆盖
+	defer func(name string) {
+		thisInfo.Name = name
+	}(thisInfo.Name)
+
 	for _, k := range keys {
-		thisInfo.Name = k
-		thisInfo.Data = fieldMap
-		v, _ := fieldMap.Get(k)
+		if err = p.parseOrderFieldItem(fieldMap, k, data, thisInfo, callBakWithStrFn, callBackWithStrSliceFn); err != nil {
+			return
+		}
+	}
+
+	return nil
+}
 
-		switch _v := v.(type) {
-		case string:
-			if v, _, err = getStrByTemplate(_v, data, thisInfo); err != nil {
+// parseOrderFieldItem 解析排序Map中的单个值
+func (p *Parser) parseOrderFieldItem(fieldMap *OrderFieldMap, k string, data map[string]interface{}, thisInfo *ThisInfo, callBakWithStrFn func(k string, v string) error, callBackWithStrSliceFn func(k string, v []string) error) (err error) {
+	thisInfo.Name = k
+	thisInfo.Data = fieldMap
+	v, _ := fieldMap.Get(k)
+
+	switch _v := v.(type) {
+	case string:
+		if _v, _, err = getStrByTemplate(_v, data, thisInfo); err != nil {
+			return
+		}
+		v = _v
+		if callBakWithStrFn != nil {
+			if err = callBakWithStrFn(k, _v); err != nil {
+				p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
+				return
+			}
+		}
+	case []string:
+		for i := range _v {
+			if _v[i], _, err = getStrByTemplate(_v[i], data, thisInfo); err != nil {
+				p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
 				return
 			}
-			if callBakWithStrFn != nil {
-				if err = callBakWithStrFn(k, _v); err != nil {
-					p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
-					return
-				}
+		}
+		if callBackWithStrSliceFn != nil {
+			if err = callBackWithStrSliceFn(k, _v); err != nil {
+				return
+			}
+		}
+	default:
+		if v, err = renderFieldValue(v, data, thisInfo); err != nil {
+			p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
+			return
+		}
+		if list, ok := v.([]interface{}); ok && callBackWithStrSliceFn != nil {
+			values := make([]string, len(list))
+			for i := range list {
+				values[i] = stringifyFieldValue(list[i])
 			}
-		case []string:
-			for i := range _v {
-				if _v[i], _, err = getStrByTemplate(_v[i], data, thisInfo); err != nil {
-					p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
-					return
-				}
+			if err = callBackWithStrSliceFn(k, values); err != nil {
+				return
 			}
-			if callBackWithStrSliceFn != nil {
-				if err = callBackWithStrSliceFn(k, _v); err != nil {
-					return
-				}
+		} else if callBakWithStrFn != nil {
+			if err = callBakWithStrFn(k, stringifyFieldValue(v)); err != nil {
+				p.LogWithPrevBlockName("%s parse err: %s", k, err.Error())
+				return
 			}
-		default:
-			return errors.New("不支持的变量类型")
 		}
-
-		p.LogWithPrevBlockName("${%s}: %s", k, v)
-		fieldMap.Set(k, v)
 	}
 
+	p.LogWithPrevBlockName("${%s}: %s", k, stringifyFieldValue(v))
+	fieldMap.Set(k, v)
 	return nil
 }
+
+// renderFieldValue 递归渲染变量值中的字符串, Map与列表将复制后返回
+func renderFieldValue(v interface{}, data map[string]interface{}, thisInfo *ThisInfo) (res interface{}, err error) {
+	switch _v := v.(type) {
+	case string:
+		res, _, err = getStrByTemplate(_v, data, thisInfo)
+		return
+	case []string:
+		list := make([]string, len(_v))
+		for i := range _v {
+			if list[i], _, err = getStrByTemplate(_v[i], data, thisInfo); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case []interface{}:
+		list := make([]interface{}, len(_v))
+		for i := range _v {
+			if list[i], err = renderFieldValue(_v[i], data, thisInfo); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(_v))
+		for k := range _v {
+			if m[k], err = renderFieldValue(_v[k], data, thisInfo); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		return v, nil
+	}
+}
+
+// stringifyFieldValue 将变量值转换为字符串, Map与列表转换为JSON
+func stringifyFieldValue(v interface{}) string {
+	switch _v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return _v
+	case []string, []interface{}, map[string]interface{}:
+		marshal, err := json.Marshal(_v)
+		if err != nil {
+			return fmt.Sprint(_v)
+		}
+		return string(marshal)
+	default:
+		return fmt.Sprint(_v)
+	}
+}
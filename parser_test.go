@@ -1,9 +1,14 @@
 package templateparser
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -22,6 +27,590 @@ func TestDecode(t *testing.T) {
 	}
 }
 
+func TestDecodeWithMocks(t *testing.T) {
+	a := assert.New(t)
+
+	mocksFilePath := filepath.Join(t.TempDir(), "mocks.yaml")
+	if !a.NoError(os.WriteFile(mocksFilePath, []byte(`
+mocks:
+  proto:
+    content: '{"data": 200, "list": [{"name": "mock", "hot": 10, "url": "https://example.com"}]}'
+`), 0666)) {
+		return
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.LoadRemoteVarMocksByFilePath(mocksFilePath)) {
+		return
+	}
+
+	if !a.NoError(parser.DecodeByFilePath("sample.yaml", &ProjectInfo{Name: "测试工程"})) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "src", "mock.txt"))
+	if a.NoError(err) {
+		a.Equal("名称: mock\n热度: 10\nUrl: https://example.com", string(content))
+	}
+}
+
+func TestDecodeTypedVars(t *testing.T) {
+	a := assert.New(t)
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+envs:
+  PORT: 8080
+vars:
+  name: demo
+  jdkVersion: 1.8
+  gradle: 1.10
+  node: 18.0
+  mode: 0755
+  replicas: 3
+  debug: false
+  tags: [api, web]
+  db:
+    host: "{{ .this.Var \"name\" }}.local"
+    port: 5432
+  modules:
+    - name: "{{ .this.Var \"name\" }}-api"
+      port: 8081
+    - name: "{{ .this.Var \"name\" }}-web"
+      port: 8082
+templates:
+  summary.txt:
+    content: |-
+      {{- $this := .this -}}
+      jdk={{ $this.Var "jdkVersion" }} replicas={{ add ($this.Var "replicas") 1 }}
+      gradle={{ $this.Var "gradle" }} node={{ $this.Var "node" }} mode={{ $this.Var "mode" }}
+      {{ if not ($this.Var "debug") }}release{{ end }} port={{ $this.Env "PORT" }} tags={{ join "," ($this.Var "tags") }}
+      db={{ ($this.Var "db").host }}:{{ ($this.Var "db").port }}
+  "{{ .v0.name }}.txt":
+    content: '{{ if gt .v0.port 8081 }}web{{ else }}api{{ end }}'
+    range: (.this.Var "modules")
+`), nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+	if a.NoError(err) {
+		a.Equal("jdk=1.8 replicas=4\ngradle=1.10 node=18.0 mode=0755\nrelease port=8080 tags=api,web\ndb=demo.local:5432", string(content))
+	}
+
+	for name, expected := range map[string]string{"demo-api.txt": "api", "demo-web.txt": "web"} {
+		content, err = os.ReadFile(filepath.Join(parser.WorkerPath, name))
+		if a.NoError(err) {
+			a.Equal(expected, string(content))
+		}
+	}
+}
+
+func TestDecodeInputs(t *testing.T) {
+	a := assert.New(t)
+
+	template := []byte(`
+inputs:
+  - name: name
+    required: true
+    pattern: ^[a-z][a-z0-9-]*$
+    max: 20
+    description: 工程名称
+  - name: port
+    type: int
+    default: 8080
+    min: 1024
+    max: 65535
+  - name: lang
+    type: enum
+    enum: [java, go]
+    default: go
+  - name: docker
+    type: bool
+  - name: modules
+    type: list
+    default: [api]
+vars:
+  image: '{{ .Inputs.name }}:{{ .Inputs.port }}'
+templates:
+  summary.txt:
+    content: '{{ .this.Var "image" }} {{ .Inputs.lang }} {{ .Inputs.docker }} {{ join "," .Inputs.modules }}'
+`)
+
+	decode := func(inputs map[string]interface{}) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.SetInputs(inputs).Decode(template, nil)
+	}
+
+	parser, err := decode(map[string]interface{}{"name": "demo", "port": "9090", "docker": "true", "modules": "api, web"})
+	if a.NoError(err) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+		if a.NoError(err) {
+			a.Equal("demo:9090 go true api,web", string(content))
+		}
+	}
+
+	_, err = decode(map[string]interface{}{"port": 80, "lang": "rust", "docker": "maybe", "unknown": 1})
+	if a.Error(err) {
+		a.Contains(err.Error(), "[name]: 缺少必填的输入参数")
+		a.Contains(err.Error(), "[port]: 值不能小于1024")
+		a.Contains(err.Error(), "[lang]: [rust]不在可选值[java, go]中")
+		a.Contains(err.Error(), "[docker]: [maybe]不是布尔值")
+		a.Contains(err.Error(), "[unknown]: 模板未声明该输入参数")
+	}
+
+	_, err = decode(map[string]interface{}{"name": "Demo"})
+	if a.Error(err) {
+		a.Contains(err.Error(), "[name]: [Demo]不匹配")
+	}
+
+	// 超出int范围的整数不能被截断
+	for _, port := range []interface{}{uint64(math.MaxUint64), 1e20, -1e20} {
+		_, err = decode(map[string]interface{}{"name": "demo", "port": port})
+		if a.Error(err, port) {
+			a.Contains(err.Error(), "[port]: ["+fmt.Sprint(port)+"]不是整数", port)
+		}
+	}
+}
+
+func TestInputsSchema(t *testing.T) {
+	a := assert.New(t)
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	info, err := parser.ParseProjectTemplateInfo([]byte(`
+inputs:
+  - name: name
+    title: 工程名称
+    required: true
+    pattern: ^[a-z]+$
+  - name: port
+    type: int
+    default: "8080"
+    min: 1024
+  - name: lang
+    type: enum
+    enum: [java, go]
+  - name: modules
+    type: list
+    max: 3
+`))
+	if !a.NoError(err) {
+		return
+	}
+
+	marshal, err := json.Marshal(info.InputsSchema())
+	if a.NoError(err) {
+		a.JSONEq(`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name"],
+  "properties": {
+    "name": {"title": "工程名称", "type": "string", "pattern": "^[a-z]+$"},
+    "port": {"title": "port", "type": "integer", "default": 8080, "minimum": 1024},
+    "lang": {"title": "lang", "type": "string", "enum": ["java", "go"]},
+    "modules": {"title": "modules", "type": "array", "items": {"type": "string"}, "maxItems": 3}
+  }
+}`, string(marshal))
+		a.Less(strings.Index(string(marshal), `"name"`), strings.Index(string(marshal), `"modules"`))
+	}
+
+	info, err = parser.ParseProjectTemplateInfo([]byte(`
+vars:
+  group: com.example
+  replicas: 2
+  image: '{{ .this.Var "group" }}'
+comments:
+  vars:
+    group: 组织名称
+`))
+	if !a.NoError(err) {
+		return
+	}
+
+	marshal, err = json.Marshal(info.InputsSchema())
+	if a.NoError(err) {
+		a.JSONEq(`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "group": {"title": "group", "description": "组织名称", "type": "string", "default": "com.example"},
+    "replicas": {"title": "replicas", "type": "integer", "default": 2},
+    "image": {"title": "image", "type": "string"}
+  }
+}`, string(marshal))
+	}
+
+	// 未设置工作路径时, 导入路径相对于模板文件的默认工作路径(模板文件所在目录的out目录)
+	dir := filepath.Join(t.TempDir(), "templates", "java")
+	if !a.NoError(os.MkdirAll(dir, 0777)) {
+		return
+	}
+	a.NoError(os.WriteFile(filepath.Join(dir, "..", "base.yaml"), []byte("inputs:\n  - name: group\n"), 0666))
+	a.NoError(os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("import: [../../base.yaml]\ninputs:\n  - name: name\n"), 0666))
+	content, err := (&Parser{}).InputsSchemaByFilePath(filepath.Join(dir, "app.yaml"))
+	if a.NoError(err) {
+		a.Contains(string(content), `"group"`)
+		a.Contains(string(content), `"name"`)
+	}
+}
+
+func TestDecodeOverrides(t *testing.T) {
+	a := assert.New(t)
+
+	valuesFilePath := filepath.Join(t.TempDir(), "values.yaml")
+	if !a.NoError(os.WriteFile(valuesFilePath, []byte(`
+vars:
+  group: org.values
+  jdkVersion: 11
+  replicas: 2
+envs:
+  MODE: values
+`), 0666)) {
+		return
+	}
+
+	t.Setenv("TPL_VAR_JDK_VERSION", "17")
+	t.Setenv("TPL_VAR_replicas", "5")
+	t.Setenv("TPL_ENV_MODE", "env")
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.LoadValuesByFilePath(valuesFilePath)) {
+		return
+	}
+	if !a.NoError(parser.SetVarByString("replicas=7")) || !a.NoError(parser.SetVarByString("db.host=db.local")) {
+		return
+	}
+
+	if !a.NoError(parser.UseEnvironmentOverrides(true).SetVar("version", "1.10").Decode([]byte(`
+envs:
+  MODE: template
+vars:
+  group: org.template
+  version: 0.0.1
+  jdkVersion: 8
+  replicas: 1
+  db:
+    host: localhost
+    port: 5432
+templates:
+  summary.txt:
+    content: |-
+      {{- $this := .this -}}
+      {{ $this.Var "group" }} {{ $this.Var "version" }} {{ $this.Var "jdkVersion" }} {{ add ($this.Var "replicas") 1 }} {{ $this.Env "MODE" }} {{ ($this.Var "db").host }}:{{ ($this.Var "db").port }}
+`), nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+	if a.NoError(err) {
+		a.Equal("org.values 1.10 17 8 env db.local:5432", string(content))
+	}
+
+	a.Equal(3, ParseOverrideValue("3"))
+	a.Equal(true, ParseOverrideValue("true"))
+	a.Equal("1.10", ParseOverrideValue(`"1.10"`))
+	a.Equal([]string{"a", "b"}, ParseOverrideValue("[a, b]"))
+	a.Equal("key: value", ParseOverrideValue("key: value"))
+	a.Equal("a # b", ParseOverrideValue("a # b"))
+}
+
+func TestDecodeProfiles(t *testing.T) {
+	a := assert.New(t)
+
+	template := []byte(`
+envs:
+  JAVA_OPTS: -Xmx512m
+vars:
+  registry: registry.dev.local
+  replicas: 1
+profiles:
+  prod:
+    envs:
+      JAVA_OPTS: -Xmx4g
+    vars:
+      registry: registry.example.com
+      replicas: 3
+    templates:
+      debug.txt:
+        ignore: true
+  ha:
+    vars:
+      replicas: 5
+    templates:
+      ha.txt:
+        content: ha
+templates:
+  summary.txt:
+    content: '{{ .this.Var "registry" }} {{ .this.Var "replicas" }} {{ .this.Env "JAVA_OPTS" }}'
+  debug.txt:
+    content: debug
+`)
+
+	decode := func(profiles ...string) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.UseProfiles(profiles...).Decode(template, nil)
+	}
+
+	parser, err := decode()
+	if a.NoError(err) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+		if a.NoError(err) {
+			a.Equal("registry.dev.local 1 -Xmx512m", string(content))
+		}
+		a.FileExists(filepath.Join(parser.WorkerPath, "debug.txt"))
+	}
+
+	parser, err = decode("prod", "ha")
+	if a.NoError(err) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+		if a.NoError(err) {
+			a.Equal("registry.example.com 5 -Xmx4g", string(content))
+		}
+		a.NoFileExists(filepath.Join(parser.WorkerPath, "debug.txt"))
+		a.FileExists(filepath.Join(parser.WorkerPath, "ha.txt"))
+	}
+
+	_, err = decode("staging")
+	if a.Error(err) {
+		a.Contains(err.Error(), "未找到配置集(profile)[staging], 可选: [ha, prod]")
+	}
+
+	// 模板未定义vars时, 启用的配置集及覆盖不应修改配置集中的定义
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	err = parser.UseProfiles("dev", "prod").SetVar("replicas", 9).Decode([]byte(`
+profiles:
+  dev:
+    vars:
+      replicas: 1
+  prod:
+    vars:
+      region: cn
+templates:
+  summary.txt:
+    content: '{{ .this.Var "replicas" }} {{ .this.Var "region" }}'
+`), nil)
+	if a.NoError(err) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "summary.txt"))
+		if a.NoError(err) {
+			a.Equal("9 cn", string(content))
+		}
+		dev := parser.TemplateInfo.Profiles["dev"].Vars
+		a.Equal([]string{"replicas"}, dev.Keys())
+		replicas, _ := dev.Get("replicas")
+		a.Equal(1, replicas)
+	}
+}
+
+func TestDecodeVarsDependencyOrder(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+	if !a.NoError(os.WriteFile(filepath.Join(dir, "version.json"), []byte(`{"version": "1.2.3"}`), 0666)) {
+		return
+	}
+
+	template := []byte(fmt.Sprintf(`
+envs:
+  IMAGE: '{{ .this.Var "image" }}'
+vars:
+  image: '{{ .this.Var "name" }}:{{ (remoteVarResponse "versionFile" .this).Data.version }}'
+  name: demo
+  versionFileName: version.json
+remoteVars:
+  versionFile:
+    type: file
+    url: '%s/{{ .this.Var "versionFileName" }}'
+    responseParser: json
+templates:
+  image.txt:
+    content: '{{ .this.Env "IMAGE" }}'
+`, filepath.ToSlash(dir)))
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if a.NoError(parser.Decode(template, nil)) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "image.txt"))
+		if a.NoError(err) {
+			a.Equal("demo:1.2.3", string(content))
+		}
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	err = parser.Decode([]byte(`
+envs:
+  A: '{{ .this.Var "b" }}'
+vars:
+  b: '{{ var "c" .this }}'
+  c: '{{ .this.Env "A" }}'
+`), nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "变量存在循环依赖: envs.A(行: 3) -> vars.b(行: 5) -> vars.c(行: 6) -> envs.A(行: 3)")
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	a := assert.New(t)
+
+	decode := func(template string, strict bool) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.UseStrictMode(strict).Decode([]byte(template), nil)
+	}
+
+	typo := `
+vars:
+  jdkVersion: "17"
+templates:
+  build.txt:
+    content: 'jdk: {{ .this.Var "jdkVerison" }}'
+`
+	parser, err := decode(typo, false)
+	if a.NoError(err) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "build.txt"))
+		if a.NoError(err) {
+			a.Equal("jdk: ", string(content))
+		}
+	}
+
+	_, err = decode(typo, true)
+	if a.Error(err) {
+		a.Contains(err.Error(), "行: 6, 列: 5, templates[build.txt]: vars[jdkVerison]未定义")
+	}
+
+	_, err = decode(`
+strict: true
+vars:
+  modules:
+    - name: api
+templates:
+  "{{ .v0.name }}.txt":
+    content: '{{ .v0.nmae }}'
+    range: '.this.Var "modules"'
+`, false)
+	if a.Error(err) {
+		a.Contains(err.Error(), "templates[{{ .v0.name }}.txt]")
+		a.Contains(err.Error(), `map has no entry for key "nmae"`)
+	}
+
+	_, err = decode(`
+envs:
+  JAVA_HOME: '/opt/jdk-{{ .this.Var "jdkVerison" }}'
+vars:
+  jdkVersion: "17"
+`, true)
+	if a.Error(err) {
+		a.Contains(err.Error(), "严格模式下不允许引用未定义的变量:\nenvs.JAVA_HOME(行: 3): 引用了未定义的 vars.jdkVerison")
+	}
+}
+
+func TestLint(t *testing.T) {
+	a := assert.New(t)
+
+	issues, err := (&Parser{}).SetOutput(io.Discard).Lint([]byte(`
+envs:
+  JAVA_HOME: '/opt/jdk-{{ .this.Var "jdkVersion" }}'
+vars:
+  jdkVersion: "17"
+  unused: value
+  debugPort: 5005
+remoteVars:
+  release:
+    type: file
+    url: '{{ .this.Var "releaseFile" }}'
+profiles:
+  debug:
+    templates:
+      debug.txt:
+        content: '{{ .this.Var "debugPort" }}'
+templates:
+  "{{ .this.Env \"JAVA_HOME\" }}/a.txt":
+    content: |
+      line1
+      {{ if .this.Var "jdkVersion" }}
+  b.txt:
+    content: '{{ (remoteVarResponse "release" .this).Data }}'
+    range: '.this.Var "modules"'
+  debug.txt:
+    content: debug
+    ignore: true
+  legacy.txt:
+    content: legacy
+    ignore: true
+  greeting.txt:
+    content: '{{ .this.Var "greeting" }} {{ .this.Var "written" }}'
+    afterWrite:
+      - run: wc -c {{ .path }}
+        capture: written
+executes:
+  pre:
+    - run: echo hello
+      capture: greeting
+  post:
+    - run: echo {{ .this.Env "PATH" }}
+`))
+	if !a.NoError(err) {
+		return
+	}
+
+	res := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		res = append(res, issue.String())
+	}
+	a.Equal([]string{
+		"行: 6, 列: 3, [warning] vars.unused: 自定义变量未被引用",
+		"行: 11, 列: 10, [error] remoteVars.release.url: 引用了未定义的 vars.releaseFile",
+		"行: 22, 列: 0, [error] templates[{{ .this.Env \"JAVA_HOME\" }}/a.txt].content: 模板语法错误: unexpected EOF",
+		"行: 24, 列: 12, [error] templates[b.txt].range: 引用了未定义的 vars.modules",
+		"行: 28, 列: 3, [warning] templates[legacy.txt]: 模板配置了 ignore: true 且没有配置集取消忽略, 将始终被忽略",
+		"行: 41, 列: 12, [error] executes.post[0].run: 引用了未定义的 envs.PATH",
+	}, res)
+
+	// 未设置工作路径时, 导入路径相对于模板文件的默认工作路径, 导入模板中的变量视为已定义
+	dir := filepath.Join(t.TempDir(), "templates", "java")
+	if !a.NoError(os.MkdirAll(dir, 0777)) {
+		return
+	}
+	a.NoError(os.WriteFile(filepath.Join(dir, "..", "base.yaml"), []byte("vars:\n  group: com.example\n"), 0666))
+	a.NoError(os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("import: [../../base.yaml]\nenvs:\n  GROUP: '{{ .this.Var \"group\" }}'\n"), 0666))
+	issues, err = (&Parser{}).LintByFilePath(filepath.Join(dir, "app.yaml"))
+	if a.NoError(err) {
+		a.Empty(issues)
+	}
+}
+
 func TestParse(t *testing.T) {
 	a := assert.New(t)
 
@@ -0,0 +1,56 @@
+package templateparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProfileInfo 配置集, 启用后覆盖模板中同名的envs、vars、remoteVars与templates
+type ProfileInfo struct {
+	// Envs 覆盖的环境变量
+	Envs *OrderFieldMap `yaml:"envs,omitempty"`
+	// Vars 覆盖的自定义变量
+	Vars *OrderFieldMap `yaml:"vars,omitempty"`
+	// RemoteVars 覆盖的动态变量
+	RemoteVars *OrderRemoteVarInfoMap `yaml:"remoteVars,omitempty"`
+	// Templates 覆盖的模板, 可通过 `ignore: true` 忽略模板中已存在的文件
+	Templates map[string]*TemplateFileInfo `yaml:"templates,omitempty"`
+}
+
+// UseProfiles 启用配置集, 多个配置集按顺序覆盖, 优先级低于values文件、环境变量与SetVar/SetEnv
+func (p *Parser) UseProfiles(names ...string) *Parser {
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			p.profiles = append(p.profiles, name)
+		}
+	}
+	return p
+}
+
+// applyProfiles 按启用顺序将配置集合并至模板
+func (p *Parser) applyProfiles() error {
+	for _, name := range p.profiles {
+		profile, ok := p.TemplateInfo.Profiles[name]
+		if !ok {
+			names := make([]string, 0, len(p.TemplateInfo.Profiles))
+			for k := range p.TemplateInfo.Profiles {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("未找到配置集(profile)[%s], 可选: [%s]", name, strings.Join(names, ", "))
+		}
+		if profile == nil {
+			continue
+		}
+
+		p.Log("profiles", "use profile => %s", name)
+		p.mergeProjectTemplateInfo(p.TemplateInfo, &ProjectTemplateInfo{
+			Envs:       profile.Envs,
+			Vars:       profile.Vars,
+			RemoteVars: profile.RemoteVars,
+			Templates:  profile.Templates,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package templateparser
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ProxyConfig 代理配置
+type ProxyConfig struct {
+	// Url 代理地址, 例: http://proxy.example.com:8080
+	Url string `yaml:"url,omitempty" json:"url,omitempty"`
+	// NoProxy 不使用代理的地址, 支持域名(包含子域名)、`.`开头的域名后缀、IP、CIDR, `*` 表示全部不使用代理
+	NoProxy []string `yaml:"noProxy,omitempty" json:"noProxy,omitempty"`
+	// Username 代理认证用户名
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	// Password 代理认证密码
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+}
+
+// key 代理配置标识, 用于复用transport
+func (c *ProxyConfig) key() string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s|%s|%s|%s", c.Url, strings.Join(c.NoProxy, ","), c.Username, c.Password)
+}
+
+// useProxy 判断主机是否需要使用代理
+func (c *ProxyConfig) useProxy(host string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+	for _, noProxy := range c.NoProxy {
+		noProxy = strings.ToLower(strings.TrimSpace(noProxy))
+		switch {
+		case noProxy == "":
+			continue
+		case noProxy == "*":
+			return false
+		case ip != nil && strings.Contains(noProxy, "/"):
+			if _, cidr, err := net.ParseCIDR(noProxy); err == nil && cidr.Contains(ip) {
+				return false
+			}
+		case strings.HasPrefix(noProxy, "."):
+			if strings.HasSuffix(host, noProxy) {
+				return false
+			}
+		case host == noProxy || strings.HasSuffix(host, "."+noProxy):
+			return false
+		}
+	}
+	return true
+}
+
+// proxyFunc 获取transport使用的代理函数, 未配置代理地址时使用环境变量中的代理配置
+func (c *ProxyConfig) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if c == nil {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	var proxyUrl *url.URL
+	if c.Url != "" {
+		var err error
+		if proxyUrl, err = url.Parse(c.Url); err != nil {
+			return nil, fmt.Errorf("错误的代理地址[%s]: %w", c.Url, err)
+		}
+
+		if c.Username != "" {
+			proxyUrl.User = url.UserPassword(c.Username, c.Password)
+		}
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if !c.useProxy(req.URL.Hostname()) {
+			return nil, nil
+		}
+
+		if proxyUrl == nil {
+			return http.ProxyFromEnvironment(req)
+		}
+		return proxyUrl, nil
+	}, nil
+}
+
+// SetProxy 设置导入模板及动态变量使用的代理, 优先级高于模板中的全局代理配置, 低于动态变量中的代理配置
+func (p *Parser) SetProxy(proxy *ProxyConfig) *Parser {
+	p.proxy = proxy
+	return p
+}
+
+// proxyConfig 获取生效的代理配置, 优先级: 动态变量 > Parser > 模板全局配置 > 环境变量
+func (p *Parser) proxyConfig(varProxy *ProxyConfig) *ProxyConfig {
+	if varProxy != nil {
+		return varProxy
+	}
+
+	if p.proxy != nil {
+		return p.proxy
+	}
+
+	if p.TemplateInfo != nil {
+		return p.TemplateInfo.Proxy
+	}
+
+	return nil
+}
+
+// httpTransport 获取代理与证书校验配置对应的transport, 相同配置复用同一transport
+func (p *Parser) httpTransport(proxy *ProxyConfig, skipVerifyCert bool) (http.RoundTripper, error) {
+	if proxy == nil {
+		if skipVerifyCert {
+			return globalSkipVerifyCertHttpClient.Transport, nil
+		}
+		return http.DefaultTransport, nil
+	}
+
+	key := fmt.Sprintf("%t|%s", skipVerifyCert, proxy.key())
+	if transport, ok := p.transports[key]; ok {
+		return transport, nil
+	}
+
+	proxyFunc, err := proxy.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxyFunc
+	if skipVerifyCert {
+		transport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	if p.transports == nil {
+		p.transports = make(map[string]http.RoundTripper)
+	}
+	p.transports[key] = transport
+	return transport, nil
+}
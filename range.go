@@ -41,8 +41,14 @@ func rangeInterface(rangeData []interface{}, data map[string]interface{}, callBa
 		return nil
 	}
 
-	t := reflect.TypeOf(rd)
 	v := reflect.ValueOf(rd)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return errors.New(fmt.Sprintf("第%s个range变量为空: ", keyIndexStr))
+		}
+		v = v.Elem()
+	}
+	t := v.Type()
 	switch t.Kind() {
 	case reflect.Slice, reflect.Array:
 		valKeyLen := v.Len()
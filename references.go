@@ -0,0 +1,402 @@
+package templateparser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// varSection 变量所属的配置段
+type varSection string
+
+const (
+	varSectionEnvs       varSection = "envs"
+	varSectionVars       varSection = "vars"
+	varSectionRemoteVars varSection = "remoteVars"
+)
+
+// varRef 模板中对envs、vars、remoteVars的引用
+type varRef struct {
+	section varSection
+	name    string
+}
+
+func (r varRef) String() string {
+	return string(r.section) + "." + r.name
+}
+
+// refFuncSections 模板方法对应引用的配置段, 例: {{ var "name" .this }}
+var refFuncSections = map[string]varSection{
+	"env":               varSectionEnvs,
+	"var":               varSectionVars,
+	"remoteVar":         varSectionRemoteVars,
+	"remoteVarResponse": varSectionRemoteVars,
+}
+
+// refMethodSections this方法对应引用的配置段, 例: {{ .this.Var "name" }}
+var refMethodSections = map[string]varSection{
+	"Env":       varSectionEnvs,
+	"Var":       varSectionVars,
+	"RemoteVar": varSectionRemoteVars,
+}
+
+// templateRefs 解析模板字符串中以字符串常量引用的envs、vars、remoteVars
+func templateRefs(str string) ([]varRef, error) {
+	if !strings.Contains(str, "{{") {
+		return nil, nil
+	}
+
+	tpl, err := template.New("refs").Funcs(textTemplateFuncs).Parse(str)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []varRef
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			walkTemplateNode(t.Tree.Root, &refs)
+		}
+	}
+	return refs, nil
+}
+
+// walkTemplateNode 遍历模板语法树收集引用
+func walkTemplateNode(node parse.Node, refs *[]varRef) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, item := range n.Nodes {
+			walkTemplateNode(item, refs)
+		}
+	case *parse.ActionNode:
+		walkTemplateNode(n.Pipe, refs)
+	case *parse.IfNode:
+		walkBranchNode(&n.BranchNode, refs)
+	case *parse.RangeNode:
+		walkBranchNode(&n.BranchNode, refs)
+	case *parse.WithNode:
+		walkBranchNode(&n.BranchNode, refs)
+	case *parse.TemplateNode:
+		walkTemplateNode(n.Pipe, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkTemplateNode(cmd, refs)
+		}
+	case *parse.ChainNode:
+		walkTemplateNode(n.Node, refs)
+	case *parse.CommandNode:
+		if ref, ok := commandRef(n); ok {
+			*refs = append(*refs, ref)
+		}
+		for _, arg := range n.Args {
+			walkTemplateNode(arg, refs)
+		}
+	}
+}
+
+func walkBranchNode(n *parse.BranchNode, refs *[]varRef) {
+	walkTemplateNode(n.Pipe, refs)
+	walkTemplateNode(n.List, refs)
+	walkTemplateNode(n.ElseList, refs)
+}
+
+// commandRef 解析命令中的引用, 仅识别名称为字符串常量的调用
+func commandRef(n *parse.CommandNode) (varRef, bool) {
+	if len(n.Args) < 2 {
+		return varRef{}, false
+	}
+	name, ok := n.Args[1].(*parse.StringNode)
+	if !ok {
+		return varRef{}, false
+	}
+
+	var (
+		section varSection
+		found   bool
+	)
+	switch fn := n.Args[0].(type) {
+	case *parse.IdentifierNode:
+		section, found = refFuncSections[fn.Ident]
+	case *parse.FieldNode:
+		section, found = lastIdentSection(fn.Ident)
+	case *parse.VariableNode:
+		section, found = lastIdentSection(fn.Ident)
+	case *parse.ChainNode:
+		section, found = lastIdentSection(fn.Field)
+	}
+	if !found {
+		return varRef{}, false
+	}
+	return varRef{section: section, name: name.Text}, true
+}
+
+func lastIdentSection(idents []string) (varSection, bool) {
+	if len(idents) == 0 {
+		return "", false
+	}
+	section, ok := refMethodSections[idents[len(idents)-1]]
+	return section, ok
+}
+
+// valueRefs 收集变量值中所有模板字符串的引用, 语法错误的模板将被忽略, 在解析时报错
+func valueRefs(v interface{}) []varRef {
+	var refs []varRef
+	walkValueRefs(reflect.ValueOf(v), &refs)
+	return refs
+}
+
+func walkValueRefs(v reflect.Value, refs *[]varRef) {
+	if !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		if fieldMap, ok := v.Interface().(*OrderFieldMap); ok {
+			if fieldMap.m == nil {
+				return
+			}
+			for _, k := range fieldMap.Keys() {
+				val, _ := fieldMap.Get(k)
+				walkValueRefs(reflect.ValueOf(val), refs)
+			}
+			return
+		}
+		walkValueRefs(v.Elem(), refs)
+	case reflect.String:
+		res, _ := templateRefs(v.String())
+		*refs = append(*refs, res...)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkValueRefs(v.Index(i), refs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			walkValueRefs(iter.Value(), refs)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() || field.Tag.Get("yaml") == "-" {
+				continue
+			}
+			walkValueRefs(v.Field(i), refs)
+		}
+	}
+}
+
+// varNode 依赖图中的变量
+type varNode struct {
+	varRef
+	line   int
+	column int
+	// deps 依赖的变量下标
+	deps []int
+}
+
+// wrapError 为envs、vars的解析错误添加变量名称及位置, remoteVars的错误已包含变量名称
+func (n *varNode) wrapError(err error) error {
+	if n.section == varSectionRemoteVars {
+		return err
+	}
+	if n.line == 0 {
+		return fmt.Errorf("%s[%s]: %w", n.section, n.name, err)
+	}
+	return fmt.Errorf("行: %d, 列: %d, %s[%s]: %w", n.line, n.column, n.section, n.name, err)
+}
+
+func (n *varNode) String() string {
+	if n.line == 0 {
+		return n.varRef.String()
+	}
+	return fmt.Sprintf("%s(行: %d)", n.varRef.String(), n.line)
+}
+
+// varGraph envs、vars、remoteVars之间的依赖图
+type varGraph struct {
+	nodes []*varNode
+	index map[varRef]int
+	// undefined 引用了未定义变量的变量, 值为未定义的引用
+	undefined map[int][]varRef
+}
+
+// buildVarGraph 通过模板中的引用构建envs、vars、remoteVars的依赖图
+func buildVarGraph(t *ProjectTemplateInfo) *varGraph {
+	g := &varGraph{
+		index:     make(map[varRef]int),
+		undefined: make(map[int][]varRef),
+	}
+
+	values := make([]interface{}, 0)
+	addFieldMap := func(section varSection, fieldMap *OrderFieldMap) {
+		if fieldMap == nil || fieldMap.m == nil {
+			return
+		}
+		for _, k := range fieldMap.Keys() {
+			v, _ := fieldMap.Get(k)
+			line, column := fieldMap.position(k)
+			g.add(&varNode{varRef: varRef{section: section, name: k}, line: line, column: column})
+			values = append(values, v)
+		}
+	}
+	addFieldMap(varSectionEnvs, t.Envs)
+	addFieldMap(varSectionVars, t.Vars)
+	if t.RemoteVars != nil && t.RemoteVars.m != nil {
+		for _, k := range t.RemoteVars.Keys() {
+			v, _ := t.RemoteVars.Get(k)
+			node := &varNode{varRef: varRef{section: varSectionRemoteVars, name: k}}
+			var value interface{}
+			if v != nil {
+				node.line, node.column = v.line, v.column
+				value = v.RemoteVarInfo
+			}
+			g.add(node)
+			values = append(values, value)
+		}
+	}
+
+	for i, node := range g.nodes {
+		seen := make(map[varRef]bool)
+		for _, ref := range valueRefs(values[i]) {
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+
+			dep, ok := g.index[ref]
+			if !ok {
+				g.undefined[i] = append(g.undefined[i], ref)
+				continue
+			}
+			node.deps = append(node.deps, dep)
+		}
+	}
+	return g
+}
+
+// undefinedError 引用了未定义变量时返回错误, 按声明顺序列出
+func (g *varGraph) undefinedError() error {
+	var problems []string
+	for i, node := range g.nodes {
+		for _, ref := range g.undefined[i] {
+			problems = append(problems, fmt.Sprintf("%s: 引用了未定义的 %s", node, ref))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("严格模式下不允许引用未定义的变量:\n%s", strings.Join(problems, "\n"))
+}
+
+func (g *varGraph) add(node *varNode) {
+	g.index[node.varRef] = len(g.nodes)
+	g.nodes = append(g.nodes, node)
+}
+
+// sort 按依赖关系排序, 无依赖关系的变量保持声明顺序(envs、vars、remoteVars), 存在循环依赖时返回错误
+func (g *varGraph) sort() ([]*varNode, error) {
+	pending := make([]int, len(g.nodes))
+	dependents := make([][]int, len(g.nodes))
+	for i, node := range g.nodes {
+		pending[i] = len(node.deps)
+		for _, dep := range node.deps {
+			dependents[dep] = append(dependents[dep], i)
+		}
+	}
+
+	done := make([]bool, len(g.nodes))
+	res := make([]*varNode, 0, len(g.nodes))
+	for len(res) < len(g.nodes) {
+		next := -1
+		for i := range g.nodes {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return nil, fmt.Errorf("变量存在循环依赖: %s", g.cycle(done))
+		}
+
+		done[next] = true
+		res = append(res, g.nodes[next])
+		for _, i := range dependents[next] {
+			pending[i]--
+		}
+	}
+	return res, nil
+}
+
+// cycle 在未排序的变量中查找一个循环依赖路径
+func (g *varGraph) cycle(done []bool) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.nodes))
+	var path []int
+
+	var visit func(i int) []int
+	visit = func(i int) []int {
+		state[i] = visiting
+		path = append(path, i)
+		for _, dep := range g.nodes[i].deps {
+			if done[dep] {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				for j := range path {
+					if path[j] == dep {
+						return append(append([]int{}, path[j:]...), dep)
+					}
+				}
+			case unvisited:
+				if res := visit(dep); res != nil {
+					return res
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+
+	for i := range g.nodes {
+		if done[i] || state[i] != unvisited {
+			continue
+		}
+		if res := visit(i); res != nil {
+			names := make([]string, len(res))
+			for j := range res {
+				names[j] = g.nodes[res[j]].String()
+			}
+			return strings.Join(names, " -> ")
+		}
+	}
+	return ""
+}
+
+// forwardRefs 引用了其后声明的变量的引用, 用于提示解析顺序已调整
+func (g *varGraph) forwardRefs(i int) []varRef {
+	var res []varRef
+	for _, dep := range g.nodes[i].deps {
+		if dep >= i {
+			res = append(res, g.nodes[dep].varRef)
+		}
+	}
+	return res
+}
@@ -2,15 +2,20 @@ package templateparser
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"gopkg.in/yaml.v3"
+	"hash"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -20,23 +25,84 @@ import (
 
 var globalSkipVerifyCertHttpClient = &http.Client{
 	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
 		},
 	},
 }
 
+// httpClient 获取动态变量使用的http客户端, 配置了会话或启用了共享cookie时携带对应的cookie jar
+func (p *Parser) httpClient(name string, varInfo *RemoteVarInfo, skipVerifyCert bool) (*http.Client, error) {
+	transport, err := p.httpTransport(p.proxyConfig(varInfo.Proxy), skipVerifyCert)
+	if err != nil {
+		return nil, err
+	}
+	transport = p.wrapFixtureTransport(name, transport)
+
+	jar, err := p.cookieJar(varInfo.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Jar:       jar,
+	}, nil
+}
+
+// importHttpClient 获取导入远程模板使用的http客户端, 代理优先级: Parser > 模板中的代理配置 > 环境变量
+func (p *Parser) importHttpClient(ctx *importContext) (*http.Client, error) {
+	proxy := p.proxy
+	if proxy == nil {
+		proxy = ctx.proxy
+	}
+
+	transport, err := p.httpTransport(proxy, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// cookieJar 获取会话对应的cookie jar, 未命名会话且未启用共享cookie时返回nil
+func (p *Parser) cookieJar(session string) (http.CookieJar, error) {
+	if session == "" && !p.sharedCookieJar {
+		return nil, nil
+	}
+
+	if p.cookieJars == nil {
+		p.cookieJars = make(map[string]http.CookieJar)
+	}
+
+	if jar, ok := p.cookieJars[session]; ok {
+		return jar, nil
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	p.cookieJars[session] = jar
+	return jar, nil
+}
+
 type RequestInterface interface {
 	// Do 请求
 	Do(p *Parser) error
 }
 
 type httpRequest struct {
-	varInfo  *RemoteVarInfo
-	req      *http.Request
-	client   *http.Client
-	data     map[string]interface{}
-	thisInfo *ThisInfo
+	varInfo *RemoteVarInfo
+	// localPath 解析本地摘要文件路径
+	localPath func(path string) string
+	req       *http.Request
+	client    *http.Client
+	data      map[string]interface{}
+	thisInfo  *ThisInfo
 }
 
 func (h *httpRequest) Do(p *Parser) error {
@@ -49,20 +115,26 @@ func (h *httpRequest) Do(p *Parser) error {
 	d := &ResponseInfo{
 		ExitCode: strconv.Itoa(res.StatusCode),
 		ExitMsg:  res.Status,
+		Headers:  res.Header,
+		Cookies:  res.Cookies(),
 		Metadata: res,
 	}
 
 	p.LogWithPrevBlockName("${%s}: response status code: %d, status text: %s", h.thisInfo.Name, res.StatusCode, res.Status)
 
 	if h.varInfo.ResponseJudge != "" {
-		h.thisInfo.Data = &d
-		if _, _, err = getStrByTemplate(h.varInfo.ResponseJudge, h.data, h.thisInfo); err != nil {
-			return errors.New(err.Error())
+		if err = judgeResponse(h.varInfo, d, h.data, h.thisInfo); err != nil {
+			return err
 		}
 	} else if res.StatusCode != 200 {
 		return errors.New(res.Status)
 	}
 
+	verifier, err := newResponseVerifier(p, h.varInfo, h.client, h.localPath, h.thisInfo)
+	if err != nil {
+		return err
+	}
+
 	resStoreDir := filepath.Join(h.thisInfo.cacheDirPath, "remoteVars", h.thisInfo.Name, "http")
 	_ = os.MkdirAll(resStoreDir, 0777)
 
@@ -73,80 +145,223 @@ func (h *httpRequest) Do(p *Parser) error {
 	}
 	defer resFile.Close()
 
-	if _, err = io.Copy(resFile, res.Body); err != nil {
+	if err = verifier.copy(resFile, res.Body, res.ContentLength); err != nil {
+		_ = resFile.Truncate(0)
 		return fmt.Errorf("remoteVars[%s]: 保存远程响应结果失败: %w", h.thisInfo.Name, err)
 	}
 
-	//resDataBytes, err := io.ReadAll(res.Body)
-	//if err != nil {
-	//	return err
-	//}
-
 	if _, err = resFile.Seek(0, 0); err != nil {
 		return fmt.Errorf("remoteVars[%s]: 复原文件指针失败: %w", h.thisInfo.Name, err)
 	}
 
-	var resData interface{} = resFilePath
-	if h.varInfo.ResponseParser != "" {
+	d.ResponseRawFilePath = resFilePath
+	if err = parseResponseData(p, h.varInfo, d, resFile, h.data, h.thisInfo); err != nil {
+		return err
+	}
+
+	h.varInfo.Response = d
+	return nil
+}
+
+// responseVerifier 响应内容校验器, 在写入缓存的同时校验大小及摘要
+type responseVerifier struct {
+	maxSize int64
+	hashes  map[string]hash.Hash
+	expects map[string]string
+}
+
+// newResponseVerifier 创建响应内容校验器, 摘要文件地址会在此时通过client获取, 本地摘要文件通过localPath解析路径
+func newResponseVerifier(p *Parser, varInfo *RemoteVarInfo, client *http.Client, localPath func(path string) string, thisInfo *ThisInfo) (*responseVerifier, error) {
+	v := &responseVerifier{
+		maxSize: int64(varInfo.MaxSize),
+		hashes:  make(map[string]hash.Hash),
+		expects: make(map[string]string),
+	}
+
+	checksums := []struct {
+		name     string
+		expect   string
+		url      string
+		hashFunc func() hash.Hash
+	}{
+		{"sha256", varInfo.Sha256, varInfo.Sha256Url, sha256.New},
+		{"sha512", varInfo.Sha512, varInfo.Sha512Url, sha512.New},
+	}
+
+	for _, c := range checksums {
+		expect := c.expect
+		if expect == "" && c.url != "" {
+			var err error
+			if expect, err = fetchChecksum(client, c.url, localPath); err != nil {
+				return nil, fmt.Errorf("remoteVars[%s]: 获取%s摘要文件[%s]失败: %w", thisInfo.Name, c.name, c.url, err)
+			}
+			p.LogWithPrevBlockName("${%s}: %s checksum from %s => %s", thisInfo.Name, c.name, c.url, expect)
+		}
+
+		if expect == "" {
+			continue
+		}
+		v.hashes[c.name] = c.hashFunc()
+		v.expects[c.name] = strings.ToLower(strings.TrimSpace(expect))
+	}
+
+	return v, nil
+}
+
+// fetchChecksum 获取摘要文件内容, 取第一个字段作为摘要值, 兼容 `sha256sum` 的输出格式.
+// 本地摘要文件的相对路径与file类型的动态变量一致, 相对于变量所在模板文件的目录
+func fetchChecksum(client *http.Client, checksumUrl string, localPath func(path string) string) (string, error) {
+	var reader io.Reader
+	if strings.HasPrefix(checksumUrl, "http://") || strings.HasPrefix(checksumUrl, "https://") {
+		res, err := client.Get(checksumUrl)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != 200 {
+			return "", errors.New(res.Status)
+		}
+		reader = res.Body
+	} else {
+		file, err := os.OpenFile(localPath(checksumUrl), os.O_RDONLY, 0666)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	content, err := io.ReadAll(io.LimitReader(reader, 64*1024))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", errors.New("摘要文件内容为空")
+	}
+	return fields[0], nil
+}
+
+// copy 拷贝内容并校验, contentLength小于0时表示长度未知
+func (v *responseVerifier) copy(dest io.Writer, src io.Reader, contentLength int64) error {
+	if v.maxSize > 0 {
+		if contentLength > v.maxSize {
+			return fmt.Errorf("内容大小[%d]超出限制[%d]", contentLength, v.maxSize)
+		}
+		src = io.LimitReader(src, v.maxSize+1)
+	}
+
+	writers := make([]io.Writer, 0, len(v.hashes)+1)
+	writers = append(writers, dest)
+	for _, h := range v.hashes {
+		writers = append(writers, h)
+	}
+
+	n, err := io.Copy(io.MultiWriter(writers...), src)
+	if err != nil {
+		return err
+	}
+
+	if v.maxSize > 0 && n > v.maxSize {
+		return fmt.Errorf("内容大小超出限制[%d]", v.maxSize)
+	}
+
+	for name, h := range v.hashes {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != v.expects[name] {
+			return fmt.Errorf("%s摘要校验失败, 期望: %s, 实际: %s", name, v.expects[name], actual)
+		}
+	}
+	return nil
+}
+
+// judgeResponse 通过responseJudge判断响应结果是否正确
+func judgeResponse(varInfo *RemoteVarInfo, d *ResponseInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	return judgeResponseBy(varInfo.ResponseJudge, d, data, thisInfo)
+}
+
+// judgeResponseBy 使用指定的模板判断响应结果
+func judgeResponseBy(judge string, d *ResponseInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	thisInfo.Data = d
+	if _, _, err := getStrByTemplate(judge, data, thisInfo); err != nil {
+		return errors.New(err.Error())
+	}
+	return nil
+}
+
+// parseResponseData 使用内置解析器与自定义解析器解析响应内容, 未配置解析器时响应数据为缓存文件路径
+func parseResponseData(p *Parser, varInfo *RemoteVarInfo, d *ResponseInfo, reader io.Reader, data map[string]interface{}, thisInfo *ThisInfo) (err error) {
+	var resData interface{} = d.ResponseRawFilePath
+	if varInfo.ResponseParser != "" {
 		var resDataBytes []byte
-		if resDataBytes, err = io.ReadAll(resFile); err != nil {
-			return fmt.Errorf("remoteVars[%s]: 读取完整内容失败: %w", h.thisInfo.Name, err)
+		if resDataBytes, err = io.ReadAll(reader); err != nil {
+			return fmt.Errorf("remoteVars[%s]: 读取完整内容失败: %w", thisInfo.Name, err)
 		}
 
-		split := strings.Split(h.varInfo.ResponseParser, "|")
+		split := strings.Split(varInfo.ResponseParser, "|")
 		for _, s := range split {
 			s = strings.TrimSpace(strings.ToLower(s))
 			switch s {
 			case "text":
 				resData = string(resDataBytes)
-				p.LogWithPrevBlockName("${%s}: response text parser => %s", h.thisInfo.Name, resData)
+				p.LogWithPrevBlockName("${%s}: response text parser => %s", thisInfo.Name, resData)
 			case "json":
 				var _d interface{}
 				if err = json.Unmarshal(resDataBytes, &_d); err != nil {
 					return err
 				}
 				resData = &_d
-				p.LogWithPrevBlockName("${%s}: response json parser => %s", h.thisInfo.Name, resDataBytes)
+				p.LogWithPrevBlockName("${%s}: response json parser => %s", thisInfo.Name, resDataBytes)
+			case "yaml":
+				var _d interface{}
+				if err = yaml.Unmarshal(resDataBytes, &_d); err != nil {
+					return err
+				}
+				resData = &_d
+				p.LogWithPrevBlockName("${%s}: response yaml parser => %s", thisInfo.Name, resDataBytes)
 			case "hex":
 				resDataBytes, err = hex.DecodeString(string(resDataBytes))
 				if err != nil {
 					return err
 				}
 				resData = resDataBytes
-				p.LogWithPrevBlockName("${%s}: response hex parser => %s", h.thisInfo.Name, resData)
+				p.LogWithPrevBlockName("${%s}: response hex parser => %s", thisInfo.Name, resData)
 			case "base64":
 				resDataBytes, err = base64.StdEncoding.DecodeString(string(resDataBytes))
 				if err != nil {
 					return err
 				}
 				resData = resDataBytes
-				p.LogWithPrevBlockName("${%s}: response base64 parser => %s", h.thisInfo.Name, resData)
+				p.LogWithPrevBlockName("${%s}: response base64 parser => %s", thisInfo.Name, resData)
 			}
 		}
 	}
 
 	d.Data = resData
 
-	if h.varInfo.PostResponseParser != "" {
-		h.thisInfo.Data = d
-		_, d.Data, err = getStrByTemplate(h.varInfo.PostResponseParser, h.data, h.thisInfo)
+	if varInfo.PostResponseParser != "" {
+		thisInfo.Data = d
+		_, d.Data, err = getStrByTemplate(varInfo.PostResponseParser, data, thisInfo)
 		if err != nil {
 			return err
 		}
 	}
-
-	h.varInfo.Response = d
 	return nil
 }
 
-func createHttpRequestByVar(varInfo *RemoteVarInfo, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) error {
+func createHttpRequestByVar(d *RemoteVarParser, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) error {
+	varInfo := d.RemoteVarInfo
 	if !strings.HasPrefix(varInfo.Url, string(varInfo.Type)) {
 		varInfo.Url = fmt.Sprintf("%s://%s", varInfo.Type, varInfo.Url)
 	}
 	p.LogWithPrevBlockName("${%s}: type => %s", thisInfo.Name, varInfo.Type)
 	p.LogWithPrevBlockName("${%s}: method => %s", thisInfo.Name, varInfo.Method)
 
-	var requestBody io.Reader
+	var (
+		requestBody io.Reader
+		contentType string
+	)
 	if varInfo.RequestParams != nil && len(varInfo.RequestParams.Keys()) > 0 {
 		buf := &bytes.Buffer{}
 		if err := p.parseOrderFieldMap(varInfo.RequestParams, data, thisInfo, func(k string, v string) error {
@@ -274,7 +489,11 @@ func createHttpRequestByVar(varInfo *RemoteVarInfo, data map[string]interface{},
 				p.LogRestore()
 			}
 
+			if err := fileWriter.Close(); err != nil {
+				return err
+			}
 			requestBody = buf
+			contentType = fileWriter.FormDataContentType()
 		}
 
 	}
@@ -284,10 +503,37 @@ func createHttpRequestByVar(varInfo *RemoteVarInfo, data map[string]interface{},
 		return err
 	}
 
+	if err = applyRequestHeaders(p, varInfo.Headers, req, data, thisInfo); err != nil {
+		return err
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	httpClient, err := p.httpClient(thisInfo.Name, varInfo, varInfo.Type == SupportRemoteReqTypeHttps && varInfo.SkipHttpsVerifyCert)
+	if err != nil {
+		return err
+	}
+
+	varInfo.Req = &httpRequest{
+		varInfo: varInfo,
+		localPath: func(path string) string {
+			return d.resolveLocalPath(p, path)
+		},
+		req:      req,
+		client:   httpClient,
+		data:     data,
+		thisInfo: thisInfo,
+	}
+
+	return nil
+}
+
+// applyRequestHeaders 设置默认请求头及配置的请求头
+func applyRequestHeaders(p *Parser, headers *OrderFieldMap, req *http.Request, data map[string]interface{}, thisInfo *ThisInfo) error {
 	req.Header.Set("User-Agent", "teamwork-lib-file-template-parser")
 	p.LogSuspend()
-	defer p.LogRestore()
-	if err = p.parseOrderFieldMap(varInfo.RequestParams, data, thisInfo, func(k string, v string) error {
+	if err := p.parseOrderFieldMap(headers, data, thisInfo, func(k string, v string) error {
 		req.Header.Add(k, v)
 		return nil
 	}, func(k string, v []string) error {
@@ -296,27 +542,13 @@ func createHttpRequestByVar(varInfo *RemoteVarInfo, data map[string]interface{},
 		}
 		return nil
 	}); err != nil {
+		p.LogRestore()
 		p.LogWithPrevBlockName("${%s}: parse header error: %s", thisInfo.Name, err.Error())
 		return err
 	}
 	p.LogRestore()
 	marshal, _ := json.Marshal(req.Header)
 	p.LogWithPrevBlockName("${%s}: header => %s", thisInfo.Name, marshal)
-
-	httpClient := http.DefaultClient
-
-	if varInfo.Type == SupportRemoteReqTypeHttps && varInfo.SkipHttpsVerifyCert {
-		httpClient = globalSkipVerifyCertHttpClient
-	}
-
-	varInfo.Req = &httpRequest{
-		varInfo:  varInfo,
-		req:      req,
-		client:   httpClient,
-		data:     data,
-		thisInfo: thisInfo,
-	}
-
 	return nil
 }
 
@@ -0,0 +1,107 @@
+package templateparser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type fileRequest struct {
+	varInfo  *RemoteVarInfo
+	filePath string
+	// localPath 解析本地摘要文件路径
+	localPath func(path string) string
+	data      map[string]interface{}
+	thisInfo  *ThisInfo
+}
+
+func (f *fileRequest) Do(p *Parser) error {
+	file, err := os.OpenFile(f.filePath, os.O_RDONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: 打开文件[%s]失败: %w", f.thisInfo.Name, f.filePath, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: 获取文件[%s]信息失败: %w", f.thisInfo.Name, f.filePath, err)
+	}
+
+	if stat.IsDir() {
+		return fmt.Errorf("remoteVars[%s]: [%s]为目录, 无法读取", f.thisInfo.Name, f.filePath)
+	}
+
+	d := &ResponseInfo{
+		ExitCode:            "0",
+		ExitMsg:             "OK",
+		ResponseRawFilePath: f.filePath,
+		Metadata:            stat,
+	}
+
+	p.LogWithPrevBlockName("${%s}: read file => %s, size: %d", f.thisInfo.Name, f.filePath, stat.Size())
+
+	if f.varInfo.ResponseJudge != "" {
+		if err = judgeResponse(f.varInfo, d, f.data, f.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	client, err := p.httpClient(f.thisInfo.Name, f.varInfo, f.varInfo.SkipHttpsVerifyCert)
+	if err != nil {
+		return err
+	}
+
+	verifier, err := newResponseVerifier(p, f.varInfo, client, f.localPath, f.thisInfo)
+	if err != nil {
+		return err
+	}
+
+	if len(verifier.hashes) > 0 || verifier.maxSize > 0 {
+		if err = verifier.copy(io.Discard, file, stat.Size()); err != nil {
+			return fmt.Errorf("remoteVars[%s]: 校验文件[%s]失败: %w", f.thisInfo.Name, f.filePath, err)
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("remoteVars[%s]: 复原文件指针失败: %w", f.thisInfo.Name, err)
+		}
+	}
+
+	if err = parseResponseData(p, f.varInfo, d, file, f.data, f.thisInfo); err != nil {
+		return err
+	}
+
+	f.varInfo.Response = d
+	return nil
+}
+
+// resolveLocalPath 解析本地路径, 相对路径优先相对于模板文件所在目录, 模板非本地文件时相对于工作目录
+func (d *RemoteVarParser) resolveLocalPath(p *Parser, path string) string {
+	path = strings.TrimPrefix(path, "file://")
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	if d.baseDir != "" {
+		return filepath.Join(d.baseDir, path)
+	}
+
+	return filepath.Join(p.WorkerPath, path)
+}
+
+func createFileRequestByVar(d *RemoteVarParser, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) error {
+	filePath := d.resolveLocalPath(p, d.Url)
+	p.LogWithPrevBlockName("${%s}: type => %s", thisInfo.Name, d.Type)
+	p.LogWithPrevBlockName("${%s}: file => %s", thisInfo.Name, filePath)
+
+	d.Req = &fileRequest{
+		varInfo:  d.RemoteVarInfo,
+		filePath: filePath,
+		localPath: func(path string) string {
+			return d.resolveLocalPath(p, path)
+		},
+		data:     data,
+		thisInfo: thisInfo,
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package templateparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+type gitRequest struct {
+	varInfo  *RemoteVarInfo
+	repoPath string
+	data     map[string]interface{}
+	thisInfo *ThisInfo
+}
+
+func (g *gitRequest) Do(p *Parser) error {
+	repo, err := openGitRepository(g.repoPath)
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: 打开git仓库失败: %w", g.thisInfo.Name, err)
+	}
+
+	info, err := repo.Info()
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: 读取git仓库信息失败: %w", g.thisInfo.Name, err)
+	}
+
+	d := &ResponseInfo{
+		ExitCode: "0",
+		ExitMsg:  "OK",
+		Data:     info,
+		Metadata: repo.gitDir,
+	}
+
+	p.LogWithPrevBlockName("${%s}: git head => %s, branch: %s, latest tag: %s", g.thisInfo.Name, info.Head, info.Branch, info.LatestTag)
+
+	if g.varInfo.ResponseJudge != "" {
+		if err = judgeResponse(g.varInfo, d, g.data, g.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	if g.varInfo.PostResponseParser != "" {
+		g.thisInfo.Data = d
+		if _, d.Data, err = getStrByTemplate(g.varInfo.PostResponseParser, g.data, g.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	g.varInfo.Response = d
+	return nil
+}
+
+func createGitRequestByVar(d *RemoteVarParser, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) error {
+	if d.MaxSize > 0 || d.Sha256 != "" || d.Sha512 != "" || d.Sha256Url != "" || d.Sha512Url != "" {
+		return errors.New(fmt.Sprintf("行: %d, 列: %d, git类型的动态变量不支持maxSize、sha256、sha512等响应内容校验配置", d.line, d.column))
+	}
+
+	repoPath := d.resolveLocalPath(p, d.Url)
+	p.LogWithPrevBlockName("${%s}: type => %s", thisInfo.Name, d.Type)
+	p.LogWithPrevBlockName("${%s}: repository => %s", thisInfo.Name, repoPath)
+
+	d.Req = &gitRequest{
+		varInfo:  d.RemoteVarInfo,
+		repoPath: repoPath,
+		data:     data,
+		thisInfo: thisInfo,
+	}
+	return nil
+}
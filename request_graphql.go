@@ -0,0 +1,184 @@
+package templateparser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// defaultGraphqlResponseJudge 未配置responseJudge时的graphql响应判断, errors不为空时失败
+const defaultGraphqlResponseJudge = `{{ with .this.Data.ErrorMessages }}{{ $.this.Error (printf "graphql响应错误: %s" .) }}{{ end }}`
+
+// graphqlRequestBody graphql请求体
+type graphqlRequestBody struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphqlError graphql响应错误
+type GraphqlError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// graphqlResponseBody graphql响应体
+type graphqlResponseBody struct {
+	Data       interface{}            `json:"data"`
+	Errors     []*GraphqlError        `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+type graphqlRequest struct {
+	varInfo *RemoteVarInfo
+	// localPath 解析本地摘要文件路径
+	localPath func(path string) string
+	req       *http.Request
+	client    *http.Client
+	data      map[string]interface{}
+	thisInfo  *ThisInfo
+}
+
+func (g *graphqlRequest) Do(p *Parser) error {
+	res, err := g.client.Do(g.req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	p.LogWithPrevBlockName("${%s}: response status code: %d, status text: %s", g.thisInfo.Name, res.StatusCode, res.Status)
+
+	verifier, err := newResponseVerifier(p, g.varInfo, g.client, g.localPath, g.thisInfo)
+	if err != nil {
+		return err
+	}
+
+	resStoreDir := filepath.Join(g.thisInfo.cacheDirPath, "remoteVars", g.thisInfo.Name, "graphql")
+	_ = os.MkdirAll(resStoreDir, 0777)
+
+	resFilePath := filepath.Join(resStoreDir, "_response.raw")
+	resFile, err := os.OpenFile(resFilePath, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0655)
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: 创建graphql响应缓存失败: %w", g.thisInfo.Name, err)
+	}
+	defer resFile.Close()
+
+	buf := &bytes.Buffer{}
+	if err = verifier.copy(io.MultiWriter(resFile, buf), res.Body, res.ContentLength); err != nil {
+		_ = resFile.Truncate(0)
+		return fmt.Errorf("remoteVars[%s]: 保存graphql响应结果失败: %w", g.thisInfo.Name, err)
+	}
+
+	d := &ResponseInfo{
+		ExitCode:            strconv.Itoa(res.StatusCode),
+		ExitMsg:             res.Status,
+		ResponseRawFilePath: resFilePath,
+		Headers:             res.Header,
+		Cookies:             res.Cookies(),
+		Metadata:            res,
+	}
+
+	resBody := &graphqlResponseBody{}
+	if err = json.Unmarshal(buf.Bytes(), resBody); err != nil {
+		if res.StatusCode != 200 {
+			return errors.New(res.Status)
+		}
+		return fmt.Errorf("remoteVars[%s]: 解析graphql响应失败: %w", g.thisInfo.Name, err)
+	}
+	d.Data = resBody.Data
+	d.Errors = resBody.Errors
+	p.LogWithPrevBlockName("${%s}: response graphql data => %s", g.thisInfo.Name, buf.Bytes())
+
+	// errors与其他类型的响应一样通过responseJudge判断, 未配置时errors不为空或状态码非200均为失败
+	judge := g.varInfo.ResponseJudge
+	if judge == "" {
+		if res.StatusCode != 200 && len(resBody.Errors) == 0 {
+			return errors.New(res.Status)
+		}
+		judge = defaultGraphqlResponseJudge
+	}
+	if err = judgeResponseBy(judge, d, g.data, g.thisInfo); err != nil {
+		return fmt.Errorf("remoteVars[%s]: %w", g.thisInfo.Name, err)
+	}
+
+	if g.varInfo.PostResponseParser != "" {
+		g.thisInfo.Data = d
+		if _, d.Data, err = getStrByTemplate(g.varInfo.PostResponseParser, g.data, g.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	g.varInfo.Response = d
+	return nil
+}
+
+func createGraphqlRequestByVar(d *RemoteVarParser, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) error {
+	varInfo := d.RemoteVarInfo
+	if strings.TrimSpace(varInfo.Query) == "" {
+		return fmt.Errorf("remoteVars[%s]: 缺失query(graphql查询语句)", thisInfo.Name)
+	}
+
+	if !strings.HasPrefix(varInfo.Url, "http://") && !strings.HasPrefix(varInfo.Url, "https://") {
+		varInfo.Url = "https://" + varInfo.Url
+	}
+	varInfo.Method = http.MethodPost
+	p.LogWithPrevBlockName("${%s}: type => %s", thisInfo.Name, varInfo.Type)
+	p.LogWithPrevBlockName("${%s}: url => %s", thisInfo.Name, varInfo.Url)
+
+	body := &graphqlRequestBody{
+		Query:         varInfo.Query,
+		OperationName: varInfo.OperationName,
+	}
+
+	if varInfo.Variables != nil && len(varInfo.Variables.Keys()) > 0 {
+		if err := p.parseOrderFieldMap(varInfo.Variables, data, thisInfo, nil, nil); err != nil {
+			return err
+		}
+		body.Variables = make(map[string]interface{})
+		for _, k := range varInfo.Variables.Keys() {
+			body.Variables[k], _ = varInfo.Variables.Get(k)
+		}
+	}
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	p.LogWithPrevBlockName("${%s}: request body => %s", thisInfo.Name, reqBody)
+
+	req, err := http.NewRequest(varInfo.Method, varInfo.Url, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if err = applyRequestHeaders(p, varInfo.Headers, req, data, thisInfo); err != nil {
+		return err
+	}
+
+	httpClient, err := p.httpClient(thisInfo.Name, varInfo, strings.HasPrefix(varInfo.Url, "https://") && varInfo.SkipHttpsVerifyCert)
+	if err != nil {
+		return err
+	}
+
+	varInfo.Req = &graphqlRequest{
+		varInfo: varInfo,
+		localPath: func(path string) string {
+			return d.resolveLocalPath(p, path)
+		},
+		req:      req,
+		client:   httpClient,
+		data:     data,
+		thisInfo: thisInfo,
+	}
+	return nil
+}
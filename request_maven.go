@@ -0,0 +1,278 @@
+package templateparser
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMavenRepositoryUrl 默认maven仓库地址
+const defaultMavenRepositoryUrl = "https://repo.maven.apache.org/maven2"
+
+// mavenMetadata maven-metadata.xml内容
+type mavenMetadata struct {
+	GroupId    string `xml:"groupId"`
+	ArtifactId string `xml:"artifactId"`
+	Versioning struct {
+		Latest      string   `xml:"latest"`
+		Release     string   `xml:"release"`
+		Versions    []string `xml:"versions>version"`
+		LastUpdated string   `xml:"lastUpdated"`
+	} `xml:"versioning"`
+}
+
+// MavenVersionInfo maven构件版本信息
+type MavenVersionInfo struct {
+	// GroupId 组
+	GroupId string
+	// ArtifactId 构件
+	ArtifactId string
+	// Latest 最新版本(包含快照)
+	Latest string
+	// Release 最新发布版本
+	Release string
+	// Versions 全部版本
+	Versions []string
+	// Matched 满足版本约束的版本, 按maven版本规则从低到高排序, 未配置版本约束时为全部正式版本
+	Matched []string
+	// LatestMatched 满足版本约束的最高版本
+	LatestMatched string
+	// LastUpdated 最后更新时间
+	LastUpdated string
+	// Skipped 配置了版本约束时, 无法转换为semver而未参与匹配的版本
+	Skipped []string
+}
+
+type mavenRequest struct {
+	varInfo     *RemoteVarInfo
+	metadataUrl string
+	// localPath 解析本地摘要文件路径
+	localPath func(path string) string
+	req       *http.Request
+	client    *http.Client
+	data      map[string]interface{}
+	thisInfo  *ThisInfo
+}
+
+func (m *mavenRequest) Do(p *Parser) error {
+	d := &ResponseInfo{
+		ExitCode: "0",
+		ExitMsg:  "OK",
+	}
+
+	var (
+		reader        io.ReadCloser
+		contentLength int64 = -1
+	)
+	if m.req == nil {
+		file, err := os.OpenFile(m.metadataUrl, os.O_RDONLY, 0666)
+		if err != nil {
+			return fmt.Errorf("remoteVars[%s]: 打开maven元数据文件失败: %w", m.thisInfo.Name, err)
+		}
+		reader = file
+		d.ResponseRawFilePath = m.metadataUrl
+	} else {
+		res, err := m.client.Do(m.req)
+		if err != nil {
+			return err
+		}
+		reader = res.Body
+		contentLength = res.ContentLength
+		d.ExitCode = strconv.Itoa(res.StatusCode)
+		d.ExitMsg = res.Status
+		d.Headers = res.Header
+		d.Cookies = res.Cookies()
+		d.Metadata = res
+		p.LogWithPrevBlockName("${%s}: response status code: %d, status text: %s", m.thisInfo.Name, res.StatusCode, res.Status)
+		if res.StatusCode != 200 {
+			_ = res.Body.Close()
+			return fmt.Errorf("remoteVars[%s]: 获取maven元数据失败: %s", m.thisInfo.Name, res.Status)
+		}
+	}
+	defer reader.Close()
+
+	client := m.client
+	if client == nil {
+		var err error
+		if client, err = p.httpClient(m.thisInfo.Name, m.varInfo, m.varInfo.SkipHttpsVerifyCert); err != nil {
+			return err
+		}
+	}
+
+	verifier, err := newResponseVerifier(p, m.varInfo, client, m.localPath, m.thisInfo)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	if err = verifier.copy(buf, reader, contentLength); err != nil {
+		return fmt.Errorf("remoteVars[%s]: 读取maven元数据失败: %w", m.thisInfo.Name, err)
+	}
+	content := buf.Bytes()
+
+	if d.ResponseRawFilePath == "" {
+		resStoreDir := filepath.Join(m.thisInfo.cacheDirPath, "remoteVars", m.thisInfo.Name, "maven")
+		_ = os.MkdirAll(resStoreDir, 0777)
+		d.ResponseRawFilePath = filepath.Join(resStoreDir, "maven-metadata.xml")
+		if err = os.WriteFile(d.ResponseRawFilePath, content, 0655); err != nil {
+			return fmt.Errorf("remoteVars[%s]: 保存maven元数据失败: %w", m.thisInfo.Name, err)
+		}
+	}
+
+	metadata := &mavenMetadata{}
+	if err = xml.NewDecoder(bytes.NewReader(content)).Decode(metadata); err != nil {
+		return fmt.Errorf("remoteVars[%s]: 解析maven元数据失败: %w", m.thisInfo.Name, err)
+	}
+
+	info, err := newMavenVersionInfo(metadata, m.varInfo.VersionConstraint)
+	if err != nil {
+		return fmt.Errorf("remoteVars[%s]: %w", m.thisInfo.Name, err)
+	}
+	d.Data = info
+	p.LogWithPrevBlockName("${%s}: maven latest => %s, release: %s, latest matched: %s", m.thisInfo.Name, info.Latest, info.Release, info.LatestMatched)
+	if len(info.Skipped) > 0 {
+		p.LogWithPrevBlockName("${%s}: warning: 以下版本无法识别, 未参与版本约束匹配: %s", m.thisInfo.Name, strings.Join(info.Skipped, ", "))
+	}
+
+	if m.varInfo.ResponseJudge != "" {
+		if err = judgeResponse(m.varInfo, d, m.data, m.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	if m.varInfo.PostResponseParser != "" {
+		m.thisInfo.Data = d
+		if _, d.Data, err = getStrByTemplate(m.varInfo.PostResponseParser, m.data, m.thisInfo); err != nil {
+			return err
+		}
+	}
+
+	m.varInfo.Response = d
+	return nil
+}
+
+// newMavenVersionInfo 通过元数据创建版本信息, 并按版本约束过滤版本
+func newMavenVersionInfo(metadata *mavenMetadata, constraint string) (*MavenVersionInfo, error) {
+	info := &MavenVersionInfo{
+		GroupId:     metadata.GroupId,
+		ArtifactId:  metadata.ArtifactId,
+		Latest:      metadata.Versioning.Latest,
+		Release:     metadata.Versioning.Release,
+		Versions:    metadata.Versioning.Versions,
+		LastUpdated: metadata.Versioning.LastUpdated,
+	}
+
+	if info.Latest == "" && len(info.Versions) > 0 {
+		info.Latest = info.Versions[len(info.Versions)-1]
+	}
+
+	var c *semver.Constraints
+	if constraint = strings.TrimSpace(constraint); constraint != "" {
+		var err error
+		if c, err = semver.NewConstraint(constraint); err != nil {
+			return nil, fmt.Errorf("错误的版本约束[%s]: %w", constraint, err)
+		}
+	}
+
+	matched := make([]*mavenVersion, 0, len(info.Versions))
+	for _, v := range info.Versions {
+		version := parseMavenVersion(v)
+		if c == nil {
+			if !version.prerelease() {
+				matched = append(matched, version)
+			}
+			continue
+		}
+
+		// 2.3.0.RELEASE、5.3.20.Final等非semver格式的版本按maven规则转换后匹配
+		semverVersion := version.semver()
+		if semverVersion == nil {
+			info.Skipped = append(info.Skipped, v)
+			continue
+		}
+		if c.Check(semverVersion) {
+			matched = append(matched, version)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].compare(matched[j]) < 0
+	})
+
+	info.Matched = make([]string, 0, len(matched))
+	for _, v := range matched {
+		info.Matched = append(info.Matched, v.raw)
+	}
+	if len(info.Matched) > 0 {
+		info.LatestMatched = info.Matched[len(info.Matched)-1]
+	}
+
+	return info, nil
+}
+
+func createMavenRequestByVar(d *RemoteVarParser, data map[string]interface{}, thisInfo *ThisInfo, p *Parser) (err error) {
+	if d.Artifact, _, err = getStrByTemplate(d.Artifact, data, thisInfo); err != nil {
+		return err
+	}
+
+	if d.VersionConstraint, _, err = getStrByTemplate(d.VersionConstraint, data, thisInfo); err != nil {
+		return err
+	}
+
+	groupId, artifactId, ok := strings.Cut(strings.TrimSpace(d.Artifact), ":")
+	if !ok || groupId == "" || artifactId == "" {
+		return errors.New(fmt.Sprintf("行: %d, 列: %d, 错误的artifact(构件坐标), 应为: group:artifact 格式", d.line, d.column))
+	}
+
+	if strings.Contains(artifactId, ":") {
+		return errors.New(fmt.Sprintf("行: %d, 列: %d, 错误的artifact(构件坐标)[%s], 应为: group:artifact 格式, 版本请通过versionConstraint配置", d.line, d.column, d.Artifact))
+	}
+
+	repoUrl := strings.TrimSuffix(d.Url, "/")
+	metadataPath := strings.ReplaceAll(groupId, ".", "/") + "/" + artifactId + "/maven-metadata.xml"
+	p.LogWithPrevBlockName("${%s}: type => %s", thisInfo.Name, d.Type)
+	p.LogWithPrevBlockName("${%s}: artifact => %s:%s", thisInfo.Name, groupId, artifactId)
+
+	req := &mavenRequest{
+		varInfo: d.RemoteVarInfo,
+		localPath: func(path string) string {
+			return d.resolveLocalPath(p, path)
+		},
+		data:     data,
+		thisInfo: thisInfo,
+	}
+	d.Req = req
+
+	if repoUrl == "" {
+		repoUrl = defaultMavenRepositoryUrl
+	}
+
+	if !strings.HasPrefix(repoUrl, "http://") && !strings.HasPrefix(repoUrl, "https://") {
+		req.metadataUrl = filepath.Join(d.resolveLocalPath(p, repoUrl), filepath.FromSlash(metadataPath))
+		p.LogWithPrevBlockName("${%s}: metadata => %s", thisInfo.Name, req.metadataUrl)
+		return nil
+	}
+
+	req.metadataUrl = repoUrl + "/" + metadataPath
+	p.LogWithPrevBlockName("${%s}: metadata => %s", thisInfo.Name, req.metadataUrl)
+
+	httpReq, err := http.NewRequest(http.MethodGet, req.metadataUrl, nil)
+	if err != nil {
+		return err
+	}
+	if err = applyRequestHeaders(p, d.Headers, httpReq, data, thisInfo); err != nil {
+		return err
+	}
+
+	req.req = httpReq
+	req.client, err = p.httpClient(thisInfo.Name, d.RemoteVarInfo, strings.HasPrefix(repoUrl, "https://") && d.SkipHttpsVerifyCert)
+	return err
+}
@@ -0,0 +1,849 @@
+package templateparser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileRemoteVar(t *testing.T) {
+	a := assert.New(t)
+
+	templateDir := t.TempDir()
+	services := []byte("- name: user\n  port: 8080\n- name: order\n  port: 8081\n")
+	if !a.NoError(os.WriteFile(filepath.Join(templateDir, "services.yaml"), services, 0666)) {
+		return
+	}
+
+	// 本地摘要文件与动态变量文件一样相对于模板文件所在目录
+	sum := sha256.Sum256(services)
+	if !a.NoError(os.WriteFile(filepath.Join(templateDir, "services.yaml.sha256"), []byte(hex.EncodeToString(sum[:])+"  services.yaml\n"), 0666)) {
+		return
+	}
+
+	templateFilePath := filepath.Join(templateDir, "template.yaml")
+	if !a.NoError(os.WriteFile(templateFilePath, []byte(`
+remoteVars:
+  services:
+    type: file
+    url: services.yaml
+    sha256Url: services.yaml.sha256
+    responseParser: yaml
+templates:
+  "{{ .v0.name }}.txt":
+    content: "{{ .v0.port }}"
+    range: ((.this | remoteVarResponse "services").Data)
+`), 0666)) {
+		return
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.DecodeByFilePath(templateFilePath, nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "order.txt"))
+	if a.NoError(err) {
+		a.Equal("8081", string(content))
+	}
+}
+
+func TestGitRemoteVar(t *testing.T) {
+	a := assert.New(t)
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	for _, objectFormat := range []string{"sha1", "sha256"} {
+		repoPath := t.TempDir()
+		git := func(args ...string) {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = repoPath
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE=1672531200 +0800", "GIT_COMMITTER_DATE=1672531200 +0800")
+			output, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("git %v: %s", args, output)
+			}
+		}
+		git("init", "-q", "-b", "main", "--object-format="+objectFormat)
+		git("config", "user.name", "tester")
+		git("config", "user.email", "tester@example.com")
+		git("remote", "add", "origin", "https://example.com/demo.git")
+		a.NoError(os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("a"), 0666))
+		git("add", "a.txt")
+		git("commit", "-q", "-m", "init")
+		git("tag", "v0.1.0")
+		// 多次修改同一文件, 打包后产生差异对象
+		for i := 0; i < 5; i++ {
+			a.NoError(os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte(strings.Repeat("line\n", 100)+strconv.Itoa(i)), 0666))
+			git("commit", "-q", "-am", "change "+strconv.Itoa(i))
+			git("tag", "-a", "v0.1."+strconv.Itoa(i+1), "-m", "patch "+strconv.Itoa(i+1))
+		}
+		a.NoError(os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("ab"), 0666))
+		git("commit", "-q", "-am", "second\n\nbody")
+		git("tag", "-a", "v0.2.0", "-m", "release")
+
+		content := []byte(`
+remoteVars:
+  repo:
+    type: git
+    url: ` + repoPath + `
+templates:
+  "version.txt":
+    content: '{{ with (.this | remoteVarResponse "repo").Data }}{{ .LatestTag }}|{{ .Branch }}|{{ .Commit.Subject }}|{{ .Commit.Author.Name }}|{{ (index .Remotes "origin").Url }}|{{ index .Config "user.email" }}|{{ len .Tags }}{{ range .Tags }}|{{ .Name }}:{{ .Annotated }}:{{ trim .Message }}{{ end }}{{ end }}'
+`)
+
+		for _, packed := range []bool{false, true} {
+			if packed {
+				// 打包所有对象及引用, 附注标签仅存在于packed-refs的peeled记录中
+				git("gc", "-q", "--aggressive")
+				_, err := os.Stat(filepath.Join(repoPath, ".git", "refs", "tags", "v0.2.0"))
+				a.True(os.IsNotExist(err))
+			}
+
+			parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+			if !a.NoError(err) {
+				return
+			}
+
+			if !a.NoError(parser.Decode(content, nil), objectFormat) {
+				return
+			}
+
+			result, err := os.ReadFile(filepath.Join(parser.WorkerPath, "version.txt"))
+			if a.NoError(err) {
+				a.Equal("v0.2.0|main|second|tester|https://example.com/demo.git|tester@example.com|7"+
+					"|v0.2.0:true:release|v0.1.5:true:patch 5|v0.1.4:true:patch 4|v0.1.3:true:patch 3"+
+					"|v0.1.2:true:patch 2|v0.1.1:true:patch 1|v0.1.0:false:", string(result), objectFormat)
+			}
+		}
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if err = parser.Decode([]byte(`
+remoteVars:
+  repo:
+    type: git
+    url: .
+    maxSize: 10
+`), nil); a.Error(err) {
+		a.Contains(err.Error(), "git类型的动态变量不支持")
+	}
+}
+
+func TestGitRemoteVarConfig(t *testing.T) {
+	a := assert.New(t)
+
+	repoPath := t.TempDir()
+	gitDir := filepath.Join(repoPath, ".git")
+	if !a.NoError(os.MkdirAll(gitDir, 0777)) {
+		return
+	}
+	files := map[string]string{
+		filepath.Join(gitDir, "HEAD"): "ref: refs/heads/main\n",
+		filepath.Join(gitDir, "config"): `[core]
+	repositoryformatversion = 0 ; comment
+[remote "origin"]
+	url = https://example.com/demo.git # comment
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+[user]
+	name = "Tester \"T\"  Name"
+	email = tester@example.com
+	signingKey = "key ; with # chars"
+[alias]
+	lg = log \
+		--oneline
+[include]
+	path = extra.config
+[includeIf "gitdir:` + filepath.ToSlash(repoPath) + `/"]
+	path = ../dir.config
+[includeIf "gitdir:/nonexistent/"]
+	path = never.config
+[includeIf "onbranch:main"]
+	path = branch.config
+`,
+		filepath.Join(gitDir, "extra.config"):  "[user]\n\temail = override@example.com\n",
+		filepath.Join(repoPath, "dir.config"):  "[custom]\n\tdir = yes\n",
+		filepath.Join(gitDir, "branch.config"): "[custom]\n\tbranch = main\n",
+		filepath.Join(gitDir, "never.config"):  "[custom]\n\tnever = yes\n",
+	}
+	for path, content := range files {
+		if !a.NoError(os.WriteFile(path, []byte(content), 0666)) {
+			return
+		}
+	}
+
+	repo, err := openGitRepository(repoPath)
+	if !a.NoError(err) {
+		return
+	}
+	info, err := repo.Info()
+	if !a.NoError(err) {
+		return
+	}
+
+	a.Equal("0", info.Config["core.repositoryformatversion"])
+	a.Equal("https://example.com/demo.git", info.Remotes["origin"].Url)
+	a.Equal([]string{"+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}, info.Remotes["origin"].Fetch)
+	a.Equal(`Tester "T"  Name`, info.Config["user.name"])
+	a.Equal("key ; with # chars", info.Config["user.signingkey"])
+	a.Equal("log   --oneline", info.Config["alias.lg"])
+	a.Equal("override@example.com", info.Config["user.email"])
+	a.Equal([]string{"tester@example.com", "override@example.com"}, info.ConfigValues["user.email"])
+	a.Equal("yes", info.Config["custom.dir"])
+	a.Equal("main", info.Config["custom.branch"])
+	a.NotContains(info.Config, "custom.never")
+
+	if !a.NoError(os.WriteFile(filepath.Join(gitDir, "config"), []byte("[user]\n\tname = \"unterminated\n"), 0666)) {
+		return
+	}
+	if _, err = openGitRepository(repoPath); a.Error(err) {
+		a.Contains(err.Error(), "引号未结束")
+	}
+}
+
+func TestGraphqlRemoteVar(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body graphqlRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Variables["name"] == "partial" {
+			_, _ = w.Write([]byte(`{"data":{"project":{"modules":["api"]}},"errors":[{"message":"web module unavailable"}]}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" || body.Variables["name"] != "demo" {
+			_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"project not found"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"project":{"modules":["api","web"]}}}`))
+	}))
+	defer server.Close()
+
+	template := func(name string) []byte {
+		return []byte(`
+vars:
+  projectName: ` + name + `
+remoteVars:
+  project:
+    type: graphql
+    url: ` + server.URL + `
+    headers:
+      Authorization: Bearer token
+    query: |
+      query($name: String!) { project(name: $name) { modules } }
+    variables:
+      name: '{{ .this.Var "projectName" }}'
+templates:
+  "{{ .v0 }}/README.md":
+    content: "{{ .v0 }}"
+    range: ((.this | remoteVarResponse "project").Data.project.modules)
+`)
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if a.NoError(parser.Decode(template("demo"), nil)) {
+		a.FileExists(filepath.Join(parser.WorkerPath, "web", "README.md"))
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	err = parser.Decode(template("missing"), nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "graphql响应错误: project not found")
+	}
+
+	// errors通过responseJudge判断, 自定义判断可接受部分数据
+	partial := strings.Replace(string(template("partial")), "    query: |", `    responseJudge: '{{ if not .this.Data.Data }}{{ .this.Error .this.Data.ErrorMessages }}{{ end }}'
+    query: |`, 1)
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if a.NoError(parser.Decode([]byte(partial), nil)) {
+		a.FileExists(filepath.Join(parser.WorkerPath, "api", "README.md"))
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if err = parser.Decode(template("partial"), nil); a.Error(err) {
+		a.Contains(err.Error(), "web module unavailable")
+	}
+
+	// 响应内容与http类型一样校验大小及摘要
+	limited := strings.Replace(string(template("demo")), "    query: |", "    maxSize: 10\n    query: |", 1)
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if err = parser.Decode([]byte(limited), nil); a.Error(err) {
+		a.Contains(err.Error(), "超出限制")
+	}
+}
+
+func TestHttpRemoteVarRequest(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Header.Get("X-Token") + "|" + r.Header.Get("name") + "|" + r.URL.RawQuery + "|" + string(body)))
+	}))
+	defer server.Close()
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	// headers作为请求头, requestParams作为查询参数, requestFormData作为请求体, 值均为渲染后的结果
+	if !a.NoError(parser.Decode([]byte(`
+vars:
+  token: t1
+  projectName: demo
+remoteVars:
+  echo:
+    type: http
+    method: POST
+    url: `+server.URL+`
+    headers:
+      X-Token: '{{ .this.Var "token" }}'
+    requestParams:
+      name: '{{ .this.Var "projectName" }}'
+    requestFormData:
+      team: '{{ .this.Var "projectName" }}-team'
+    responseParser: text
+templates:
+  "echo.txt":
+    content: '{{ (.this | remoteVarResponse "echo").Data }}'
+`), nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "echo.txt"))
+	if a.NoError(err) {
+		a.Equal("t1||name=demo|team=demo-team", string(content))
+	}
+}
+
+func TestHttpRemoteVarHeaders(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(r.Header.Values("X-Tag"), ",") + "|" + r.Header.Get("version") + "|" + r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	// 请求头仅来自headers, requestParams不再作为请求头发送
+	if !a.NoError(parser.Decode([]byte(`
+vars:
+  version: "1.0"
+remoteVars:
+  echo:
+    type: http
+    url: `+server.URL+`
+    headers:
+      X-Tag: [a, '{{ .this.Var "version" }}']
+    requestParams:
+      version: '{{ .this.Var "version" }}'
+    responseParser: text
+templates:
+  "echo.txt":
+    content: '{{ (.this | remoteVarResponse "echo").Data }}'
+`), nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "echo.txt"))
+	if a.NoError(err) {
+		a.Equal("a,1.0||version=1.0", string(content))
+	}
+}
+
+func TestMavenRemoteVar(t *testing.T) {
+	a := assert.New(t)
+
+	repoPath := t.TempDir()
+	metadataDir := filepath.Join(repoPath, "org", "springframework", "boot", "spring-boot")
+	if !a.NoError(os.MkdirAll(metadataDir, 0777)) {
+		return
+	}
+	if !a.NoError(os.WriteFile(filepath.Join(metadataDir, "maven-metadata.xml"), []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>org.springframework.boot</groupId>
+  <artifactId>spring-boot</artifactId>
+  <versioning>
+    <latest>3.1.0-RC1</latest>
+    <release>3.0.6</release>
+    <versions>
+      <version>2.7.11</version>
+      <version>3.0.0</version>
+      <version>3.0.6</version>
+      <version>3.1.0-RC1</version>
+    </versions>
+  </versioning>
+</metadata>`), 0666)) {
+		return
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if !a.NoError(parser.Decode([]byte(`
+remoteVars:
+  springBoot:
+    type: maven
+    url: file://`+repoPath+`
+    artifact: org.springframework.boot:spring-boot
+    versionConstraint: ~3.0.0
+templates:
+  "version.txt":
+    content: '{{ with (.this | remoteVarResponse "springBoot").Data }}{{ .Latest }}|{{ .Release }}|{{ .LatestMatched }}{{ end }}'
+`), nil)) {
+		return
+	}
+
+	content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "version.txt"))
+	if a.NoError(err) {
+		a.Equal("3.1.0-RC1|3.0.6|3.0.6", string(content))
+	}
+
+	// 非semver格式的版本按maven版本规则排序及匹配, 无法识别的版本单独列出
+	metadataDir = filepath.Join(repoPath, "org", "hibernate", "hibernate-core")
+	if !a.NoError(os.MkdirAll(metadataDir, 0777)) {
+		return
+	}
+	if !a.NoError(os.WriteFile(filepath.Join(metadataDir, "maven-metadata.xml"), []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<metadata>
+  <groupId>org.hibernate</groupId>
+  <artifactId>hibernate-core</artifactId>
+  <versioning>
+    <versions>
+      <version>5.3.20.Final</version>
+      <version>5.3.3.RELEASE</version>
+      <version>5.3.9</version>
+      <version>5.3.20.SP1</version>
+      <version>6.0.0-M1</version>
+      <version>6.0.0-RC2</version>
+      <version>6.0.0.Final</version>
+      <version>6.2.0.Final</version>
+      <version>nightly</version>
+    </versions>
+  </versioning>
+</metadata>`), 0666)) {
+		return
+	}
+
+	decode := func(artifact, constraint string) (*Parser, error) {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return nil, err
+		}
+		return parser, parser.Decode([]byte(`
+remoteVars:
+  hibernate:
+    type: maven
+    url: file://`+repoPath+`
+    artifact: `+artifact+`
+    versionConstraint: "`+constraint+`"
+templates:
+  "version.txt":
+    content: '{{ with (.this | remoteVarResponse "hibernate").Data }}{{ join "," .Matched }}|{{ .LatestMatched }}|{{ join "," .Skipped }}{{ end }}'
+`), nil)
+	}
+
+	for _, item := range []struct {
+		constraint string
+		expect     string
+	}{
+		{">=5.3.0 <6.1.0", "5.3.3.RELEASE,5.3.9,5.3.20.Final,5.3.20.SP1,6.0.0.Final|6.0.0.Final|nightly"},
+		{"", "nightly,5.3.3.RELEASE,5.3.9,5.3.20.Final,5.3.20.SP1,6.0.0.Final,6.2.0.Final|6.2.0.Final|"},
+		{">=6.0.0-0", "6.0.0-M1,6.0.0-RC2,6.0.0.Final,6.2.0.Final|6.2.0.Final|nightly"},
+	} {
+		parser, err = decode("org.hibernate:hibernate-core", item.constraint)
+		if !a.NoError(err, item.constraint) {
+			continue
+		}
+		content, err = os.ReadFile(filepath.Join(parser.WorkerPath, "version.txt"))
+		if a.NoError(err) {
+			a.Equal(item.expect, string(content), item.constraint)
+		}
+	}
+
+	if _, err = decode("org.hibernate:hibernate-core:5.3.20.Final", ""); a.Error(err) {
+		a.Contains(err.Error(), "错误的artifact(构件坐标)[org.hibernate:hibernate-core:5.3.20.Final]")
+	}
+
+	// 元数据与http类型的响应一样校验摘要及大小
+	if _, err = decode("org.hibernate:hibernate-core\n    sha256: "+strings.Repeat("0", 64), ""); a.Error(err) {
+		a.Contains(err.Error(), "sha256摘要校验失败")
+	}
+	if _, err = decode("org.hibernate:hibernate-core\n    maxSize: 10", ""); a.Error(err) {
+		a.Contains(err.Error(), "超出限制")
+	}
+}
+
+func TestHttpRemoteVarVerify(t *testing.T) {
+	a := assert.New(t)
+
+	content := []byte("gradle-wrapper-jar-content")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gradle-wrapper.jar.sha256" {
+			_, _ = w.Write([]byte(checksum + "  gradle-wrapper.jar\n"))
+			return
+		}
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	decode := func(verify string) error {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if err != nil {
+			return err
+		}
+
+		return parser.Decode([]byte(`
+remoteVars:
+  jar:
+    type: http
+    url: `+server.URL+`/gradle-wrapper.jar
+`+verify+`
+templates:
+  "gradle-wrapper.jar":
+    path: '{{ (.this | remoteVarResponse "jar").Data }}'
+`), nil)
+	}
+
+	a.NoError(decode("    sha256: " + checksum))
+	a.NoError(decode("    sha256Url: " + server.URL + "/gradle-wrapper.jar.sha256\n    maxSize: 1KB"))
+
+	if err := decode("    sha256: " + checksum[1:] + "0"); a.Error(err) {
+		a.Contains(err.Error(), "sha256")
+	}
+
+	if err := decode("    maxSize: 10"); a.Error(err) {
+		a.Contains(err.Error(), "超出限制")
+	}
+}
+
+func TestRemoteVarSession(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("X-Login-User", "admin")
+			http.SetCookie(w, &http.Cookie{Name: "SESSION", Value: "s1", Path: "/"})
+		case "/me":
+			if c, err := r.Cookie("SESSION"); err != nil || c.Value != "s1" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte("admin"))
+		}
+	}))
+	defer server.Close()
+
+	template := func(session string) []byte {
+		return []byte(`
+remoteVars:
+  login:
+    type: http
+    url: ` + server.URL + `/login
+    session: ` + session + `
+  me:
+    type: http
+    url: ` + server.URL + `/me
+    session: ` + session + `
+    responseParser: text
+templates:
+  "me.txt":
+    content: '{{ (.this | remoteVarResponse "login").Header "X-Login-User" }}|{{ (.this | remoteVarResponse "login").Cookie "SESSION" }}|{{ (.this | remoteVarResponse "me").Data }}'
+`)
+	}
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if a.NoError(parser.Decode(template("portal"), nil)) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "me.txt"))
+		if a.NoError(err) {
+			a.Equal("admin|s1|admin", string(content))
+		}
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	a.Error(parser.Decode(template(`""`), nil))
+	a.NoError(parser.UseSharedCookieJar(true).Decode(template(`""`), nil))
+}
+
+func TestRemoteVarProxy(t *testing.T) {
+	a := assert.New(t)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("direct"))
+	}))
+	defer target.Close()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	content := []byte(`
+proxy:
+  url: ` + proxy.URL + `
+  username: user
+  password: pass
+remoteVars:
+  viaProxy:
+    type: http
+    url: ` + target.URL + `
+    responseParser: text
+  direct:
+    type: http
+    url: ` + target.URL + `
+    responseParser: text
+    proxy:
+      noProxy: ["*"]
+templates:
+  "result.txt":
+    content: '{{ (.this | remoteVarResponse "viaProxy").Data }}|{{ (.this | remoteVarResponse "direct").Data }}'
+`)
+
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	if a.NoError(parser.Decode(content, nil)) {
+		result, err := os.ReadFile(filepath.Join(parser.WorkerPath, "result.txt"))
+		if a.NoError(err) {
+			a.Equal("proxied|direct", string(result))
+		}
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+
+	err = parser.SetProxy(&ProxyConfig{Url: proxy.URL}).Decode(content, nil)
+	if a.Error(err) {
+		a.Contains(err.Error(), "407")
+	}
+}
+
+func TestRemoteVarRecordReplay(t *testing.T) {
+	a := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"version":"1.2.3"}`))
+	}))
+
+	template := func(path string) []byte {
+		return []byte(`
+remoteVars:
+  release:
+    type: http
+    url: ` + server.URL + path + `
+    responseParser: json
+templates:
+  "version.txt":
+    content: '{{ (.this | remoteVarResponse "release").Data.version }}'
+`)
+	}
+
+	fixtureDir := t.TempDir()
+	parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if !a.NoError(parser.SetRecordMode(fixtureDir).Decode(template("/release"), nil)) {
+		return
+	}
+	server.Close()
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if a.NoError(parser.SetReplayMode(fixtureDir).Decode(template("/release"), nil)) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "version.txt"))
+		if a.NoError(err) {
+			a.Equal("1.2.3", string(content))
+		}
+	}
+
+	if err = parser.Decode(template("/unknown"), nil); a.Error(err) {
+		a.Contains(err.Error(), "未找到录制的请求")
+	}
+
+	// multipart请求体的分隔符每次随机生成, 不影响回放
+	uploadServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		_, _ = w.Write([]byte(header.Filename + ":" + string(content) + ":" + r.FormValue("name")))
+	}))
+
+	uploadPath := filepath.Join(t.TempDir(), "upload.txt")
+	a.NoError(os.WriteFile(uploadPath, []byte("content"), 0666))
+	uploadTemplate := func(name string) []byte {
+		return []byte(`
+remoteVars:
+  upload:
+    type: http
+    method: POST
+    url: ` + uploadServer.URL + `
+    requestUploadFiles:
+      files:
+        file: ` + uploadPath + `
+      data:
+        name: ` + name + `
+    responseParser: text
+templates:
+  "upload.txt":
+    content: '{{ (.this | remoteVarResponse "upload").Data }}'
+`)
+	}
+
+	fixtureDir = t.TempDir()
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if !a.NoError(parser.SetRecordMode(fixtureDir).Decode(uploadTemplate("demo"), nil)) {
+		return
+	}
+	uploadServer.Close()
+	bodyFiles, _ := filepath.Glob(filepath.Join(fixtureDir, "upload", "*.body"))
+	if a.Len(bodyFiles, 1) {
+		content, err := os.ReadFile(bodyFiles[0])
+		if a.NoError(err) {
+			a.Equal("upload.txt:content:demo", string(content))
+		}
+	}
+
+	parser, err = NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+	if !a.NoError(err) {
+		return
+	}
+	if a.NoError(parser.SetReplayMode(fixtureDir).Decode(uploadTemplate("demo"), nil)) {
+		content, err := os.ReadFile(filepath.Join(parser.WorkerPath, "upload.txt"))
+		if a.NoError(err) {
+			a.Equal("upload.txt:content:demo", string(content))
+		}
+	}
+
+	if err = parser.Decode(uploadTemplate("other"), nil); a.Error(err) {
+		a.Contains(err.Error(), "未找到录制的请求")
+	}
+}
+
+func TestImportProxy(t *testing.T) {
+	a := assert.New(t)
+
+	newProxy := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host != "templates.example" {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			_, _ = w.Write([]byte("vars:\n  via: " + name + "\n"))
+		}))
+	}
+	templateProxy := newProxy("template")
+	defer templateProxy.Close()
+	parserProxy := newProxy("parser")
+	defer parserProxy.Close()
+
+	content := []byte(`
+proxy:
+  url: ` + templateProxy.URL + `
+import:
+  - http://templates.example/base.yaml
+templates:
+  "result.txt":
+    content: '{{ .this.Var "via" }}'
+`)
+
+	for _, item := range []struct {
+		proxy  *ProxyConfig
+		expect string
+	}{
+		{nil, "template"},
+		{&ProxyConfig{Url: parserProxy.URL}, "parser"},
+	} {
+		parser, err := NewParserByWorkPath(filepath.Join(t.TempDir(), "out"))
+		if !a.NoError(err) {
+			return
+		}
+		if item.proxy != nil {
+			parser.SetProxy(item.proxy)
+		}
+
+		if a.NoError(parser.Decode(content, nil)) {
+			result, err := os.ReadFile(filepath.Join(parser.WorkerPath, "result.txt"))
+			if a.NoError(err) {
+				a.Equal(item.expect, string(result))
+			}
+		}
+	}
+}
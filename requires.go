@@ -0,0 +1,212 @@
+package templateparser
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// defaultVersionPattern 默认的版本号匹配表达式
+var defaultVersionPattern = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// requireVersionTimeout 获取版本号命令的超时时间
+const requireVersionTimeout = 30 * time.Second
+
+// RequireInfo 执行命令前需要存在的可执行文件, 支持仅配置可执行文件名称的字符串形式
+type RequireInfo struct {
+	// Name 可执行文件名称或路径
+	Name string `yaml:"name"`
+	// Version 版本约束, 例: >=17, ^1.20, ~8.5
+	Version string `yaml:"version,omitempty"`
+	// VersionCommand 获取版本号的命令, 默认: `<name> --version`
+	VersionCommand string `yaml:"versionCommand,omitempty"`
+	// VersionPattern 从命令输出中提取版本号的正则表达式, 存在分组时使用第一个分组, 默认匹配首个形如1.2.3的版本号
+	VersionPattern string `yaml:"versionPattern,omitempty"`
+	// Optional 为true时仅输出警告, 不终止解析
+	Optional bool `yaml:"optional,omitempty"`
+	// Hint 缺失时的提示信息, 例如安装方式
+	Hint string `yaml:"hint,omitempty"`
+
+	line int
+}
+
+func (r *RequireInfo) UnmarshalYAML(value *yaml.Node) error {
+	r.line = value.Line
+	if value.Kind == yaml.ScalarNode {
+		r.Name = value.Value
+	} else {
+		type requireInfo RequireInfo
+		if err := value.Decode((*requireInfo)(r)); err != nil {
+			return err
+		}
+	}
+
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("行: %d, 列: %d, requires缺失name(可执行文件名称)", value.Line, value.Column)
+	}
+	return nil
+}
+
+// RequireResult 可执行文件检查结果
+type RequireResult struct {
+	// Name 可执行文件名称
+	Name string
+	// Path 可执行文件路径, 未找到时为空
+	Path string
+	// Version 检测到的版本号
+	Version string
+	// Constraint 版本约束
+	Constraint string
+	// Optional 是否可选
+	Optional bool
+	// Err 检查失败原因, 满足要求时为nil
+	Err error
+}
+
+// checkRequires 检查模板所需的可执行文件, 必需项不满足时汇总返回错误, 可选项不满足时仅输出警告
+func (p *Parser) checkRequires(requires []*RequireInfo, data map[string]interface{}, thisInfo *ThisInfo) error {
+	if len(requires) == 0 {
+		return nil
+	}
+
+	if p.executePolicy != nil && p.executePolicy.Disabled {
+		p.LogWithPrevBlockName("executes disabled, skip requires check")
+		return nil
+	}
+
+	var missing []string
+	for _, require := range requires {
+		result := p.checkRequire(require, data, thisInfo)
+		if result.Err == nil {
+			p.LogWithPrevBlockName("${%s} => %s, version: %s", result.Name, result.Path, result.Version)
+			continue
+		}
+
+		msg := fmt.Sprintf("[%s]: %s", result.Name, result.Err.Error())
+		if require.Hint != "" {
+			msg += ", " + require.Hint
+		}
+		if result.Optional {
+			p.LogWithPrevBlockName("warning: %s", msg)
+			continue
+		}
+		p.LogWithPrevBlockName("error: %s", msg)
+		missing = append(missing, msg)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("以下必需的可执行文件不满足要求:\n%s", strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// checkRequire 检查单个可执行文件是否存在及版本是否满足约束
+func (p *Parser) checkRequire(require *RequireInfo, data map[string]interface{}, thisInfo *ThisInfo) (result *RequireResult) {
+	result = &RequireResult{
+		Name:     require.Name,
+		Optional: require.Optional,
+	}
+
+	var err error
+	if result.Name, _, err = getStrByTemplate(require.Name, data, thisInfo); err != nil {
+		result.Err = err
+		return
+	}
+	result.Name = strings.TrimSpace(result.Name)
+
+	if result.Constraint, _, err = getStrByTemplate(require.Version, data, thisInfo); err != nil {
+		result.Err = err
+		return
+	}
+	result.Constraint = strings.TrimSpace(result.Constraint)
+
+	if p.executePolicy != nil {
+		if err = p.executePolicy.checkExecutable(result.Name, result.Name); err != nil {
+			result.Err = err
+			return
+		}
+	}
+
+	if result.Path, err = exec.LookPath(result.Name); err != nil {
+		result.Err = errors.New("未找到可执行文件")
+		return
+	}
+
+	if result.Constraint == "" {
+		return
+	}
+
+	constraint, err := semver.NewConstraint(result.Constraint)
+	if err != nil {
+		result.Err = fmt.Errorf("行: %d, 错误的版本约束[%s]: %w", require.line, result.Constraint, err)
+		return
+	}
+
+	if result.Version, err = p.requireVersion(require, result, data, thisInfo); err != nil {
+		result.Err = err
+		return
+	}
+
+	version, err := semver.NewVersion(result.Version)
+	if err != nil {
+		result.Err = fmt.Errorf("无法识别的版本号[%s]", result.Version)
+		return
+	}
+
+	if !constraint.Check(version) {
+		result.Err = fmt.Errorf("版本[%s]不满足[%s]", result.Version, result.Constraint)
+	}
+	return
+}
+
+// requireVersion 执行版本命令并提取版本号, 命令的标准输出与错误输出均参与匹配
+func (p *Parser) requireVersion(require *RequireInfo, result *RequireResult, data map[string]interface{}, thisInfo *ThisInfo) (string, error) {
+	command := result.Name + " --version"
+	if require.VersionCommand != "" {
+		var err error
+		if command, _, err = getStrByTemplate(require.VersionCommand, data, thisInfo); err != nil {
+			return "", err
+		}
+	}
+
+	pattern := defaultVersionPattern
+	if require.VersionPattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(require.VersionPattern); err != nil {
+			return "", fmt.Errorf("行: %d, 错误的版本匹配表达式[%s]: %w", require.line, require.VersionPattern, err)
+		}
+	}
+
+	// 版本命令按参数数组直接执行, 与其他命令一样经过执行策略校验
+	shell := NewShellConfig(shellModeExec)
+	output := &bytes.Buffer{}
+	opts := &ExecOptions{
+		Command: command,
+		Dir:     p.WorkerPath,
+		Timeout: requireVersionTimeout,
+		Stdout:  output,
+		Stderr:  output,
+	}
+	if err := p.checkExecutePolicy(shell, opts); err != nil {
+		return "", err
+	}
+	if err := shell.Exec(context.Background(), opts); err != nil {
+		return "", fmt.Errorf("获取版本号失败[%s]: %w", command, err)
+	}
+
+	match := pattern.FindStringSubmatch(output.String())
+	if len(match) == 0 {
+		return "", fmt.Errorf("未能从命令[%s]的输出中识别版本号", command)
+	}
+	if len(match) > 1 && match[1] != "" && require.VersionPattern != "" {
+		return match[1], nil
+	}
+	return match[0], nil
+}
@@ -0,0 +1,155 @@
+package templateparser
+
+import (
+	"encoding/json"
+	"github.com/iancoleman/orderedmap"
+	"strings"
+)
+
+// jsonSchemaDraft 导出的JSON Schema版本
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema 模板输入参数的JSON Schema
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type,omitempty"`
+	// Properties 属性, 值为*JSONSchema, 按声明顺序输出
+	Properties           *orderedmap.OrderedMap `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	Maximum              *int                   `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+}
+
+// InputsSchema 导出模板输入参数的JSON Schema, 未声明inputs时使用vars及comments.vars生成
+func (t *ProjectTemplateInfo) InputsSchema() *JSONSchema {
+	schema := &JSONSchema{
+		Schema:     jsonSchemaDraft,
+		Type:       "object",
+		Properties: orderedmap.New(),
+	}
+
+	if len(t.Inputs) > 0 {
+		// 未声明的输入参数将校验失败
+		additionalProperties := false
+		schema.AdditionalProperties = &additionalProperties
+		for _, input := range t.Inputs {
+			schema.Properties.Set(input.Name, input.schema())
+			if input.Required && input.Default == nil {
+				schema.Required = append(schema.Required, input.Name)
+			}
+		}
+		return schema
+	}
+
+	if t.Vars == nil || t.Vars.m == nil {
+		return schema
+	}
+
+	var comments map[string]string
+	if t.Comments != nil {
+		comments = t.Comments.Vars
+	}
+	for _, k := range t.Vars.Keys() {
+		v, _ := t.Vars.Get(k)
+		property := valueSchema(v)
+		property.Title = k
+		property.Description = comments[k]
+		schema.Properties.Set(k, property)
+	}
+	return schema
+}
+
+// schema 输入参数对应的JSON Schema
+func (i *InputInfo) schema() *JSONSchema {
+	schema := &JSONSchema{
+		Title:       i.Title,
+		Description: i.Description,
+		Default:     i.Default,
+	}
+	if schema.Title == "" {
+		schema.Title = i.Name
+	}
+
+	switch i.Type {
+	case InputTypeInt:
+		schema.Type = "integer"
+		schema.Minimum, schema.Maximum = i.Min, i.Max
+	case InputTypeBool:
+		schema.Type = "boolean"
+	case InputTypeList:
+		schema.Type = "array"
+		schema.Items = &JSONSchema{
+			Type:    "string",
+			Enum:    i.Enum,
+			Pattern: i.Pattern,
+		}
+		schema.MinItems, schema.MaxItems = i.Min, i.Max
+	default:
+		schema.Type = "string"
+		schema.Enum = i.Enum
+		schema.Pattern = i.Pattern
+		schema.MinLength, schema.MaxLength = i.Min, i.Max
+	}
+
+	if i.Default != nil {
+		if v, err := i.convert(i.Default); err == nil {
+			schema.Default = v
+		}
+	}
+	return schema
+}
+
+// valueSchema 按变量值推断JSON Schema, 包含模板表达式的值不作为默认值
+func valueSchema(v interface{}) *JSONSchema {
+	schema := &JSONSchema{}
+	switch _v := v.(type) {
+	case string:
+		schema.Type = "string"
+		if !strings.Contains(_v, "{{") {
+			schema.Default = _v
+		}
+		return schema
+	case bool:
+		schema.Type = "boolean"
+	case int, int64, uint64:
+		schema.Type = "integer"
+	case float64:
+		schema.Type = "number"
+	case []string:
+		schema.Type = "array"
+		schema.Items = &JSONSchema{Type: "string"}
+	case []interface{}:
+		schema.Type = "array"
+	case map[string]interface{}:
+		schema.Type = "object"
+	default:
+		return schema
+	}
+
+	if marshal, err := json.Marshal(v); err != nil || strings.Contains(string(marshal), "{{") {
+		return schema
+	}
+	schema.Default = v
+	return schema
+}
+
+// InputsSchemaByFilePath 解析模板文件(包含导入的模板)并导出输入参数的JSON Schema,
+// 未设置工作路径时本地导入路径相对于模板文件的默认工作路径
+func (p *Parser) InputsSchemaByFilePath(filePath string) ([]byte, error) {
+	info, err := p.parseProjectTemplateInfoByFilePath(filePath, p.importContextByFilePath(filePath))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(info.InputsSchema(), "", "  ")
+}
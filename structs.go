@@ -1,14 +1,12 @@
 package templateparser
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/iancoleman/orderedmap"
 	"gopkg.in/yaml.v3"
-	"os"
-	"os/exec"
-	"runtime"
+	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +19,12 @@ const (
 	ThisTypeTemplates   ThisType = "templates"
 	ThisTypeExecutePre  ThisType = "executes-pre"
 	ThisTypeExecutePost ThisType = "executes-post"
+
+	ThisTypeExecuteAfterEnvs       ThisType = "executes-afterEnvs"
+	ThisTypeExecuteAfterVars       ThisType = "executes-afterVars"
+	ThisTypeExecuteAfterRemoteVars ThisType = "executes-afterRemoteVars"
+	ThisTypeExecuteAfterWrite      ThisType = "executes-afterWrite"
+	ThisTypeRequires               ThisType = "requires"
 )
 
 type TemplateFileInfo struct {
@@ -36,6 +40,28 @@ type TemplateFileInfo struct {
 	Comment string `yaml:"comment,omitempty"`
 	// Ignore 忽略
 	Ignore bool `yaml:"ignore,omitempty"`
+	// AfterWrite 文件(目录)写入后执行的命令, 命令模板中可通过 .path(相对于工作目录的路径)、.absPath(绝对路径)、.key(模板key) 获取写入信息
+	AfterWrite []*ExecuteCommand `yaml:"afterWrite,omitempty"`
+
+	line   int
+	column int
+}
+
+func (t *TemplateFileInfo) UnmarshalYAML(value *yaml.Node) error {
+	type templateFileInfo TemplateFileInfo
+	if err := value.Decode((*templateFileInfo)(t)); err != nil {
+		return err
+	}
+	t.line, t.column = value.Line, value.Column
+	return nil
+}
+
+// wrapError 为模板的解析错误添加模板key及位置
+func (t *TemplateFileInfo) wrapError(key string, err error) error {
+	if t.line == 0 {
+		return fmt.Errorf("templates[%s]: %w", key, err)
+	}
+	return fmt.Errorf("行: %d, 列: %d, templates[%s]: %w", t.line, t.column, key, err)
 }
 
 type ResponseInfo struct {
@@ -43,15 +69,63 @@ type ResponseInfo struct {
 	ExitMsg             string
 	ResponseRawFilePath string
 	Data                interface{}
-	Metadata            interface{}
+	// Headers http响应头
+	Headers http.Header
+	// Cookies http响应设置的cookie
+	Cookies []*http.Cookie
+	// Errors graphql响应中的errors, 可在responseJudge中判断
+	Errors   []*GraphqlError
+	Metadata interface{}
+}
+
+// Header 获取响应头
+func (r *ResponseInfo) Header(name string) string {
+	if r.Headers == nil {
+		return ""
+	}
+	return r.Headers.Get(name)
+}
+
+// ErrorMessages 获取graphql响应errors中的错误信息, 以 `; ` 分隔
+func (r *ResponseInfo) ErrorMessages() string {
+	messages := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Cookie 获取响应设置的cookie值
+func (r *ResponseInfo) Cookie(name string) string {
+	for _, c := range r.Cookies {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
 }
 
 type OrderFieldMap struct {
 	m *orderedmap.OrderedMap
+	// positions 键在YAML中的位置
+	positions map[string]fieldPosition
+}
+
+// fieldPosition YAML中的位置
+type fieldPosition struct {
+	line   int
+	column int
+}
+
+func NewOrderFieldMap() *OrderFieldMap {
+	return &OrderFieldMap{
+		m: orderedmap.New(),
+	}
 }
 
 func (o *OrderFieldMap) UnmarshalYAML(value *yaml.Node) error {
 	r := orderedmap.New()
+	o.positions = make(map[string]fieldPosition)
 	contentLen := len(value.Content)
 	switch value.Kind {
 	case yaml.MappingNode:
@@ -62,32 +136,12 @@ func (o *OrderFieldMap) UnmarshalYAML(value *yaml.Node) error {
 			key := value.Content[i]
 			val := value.Content[i+1]
 
-			//if val.Kind != key.Kind {
-			//	res := new(any)
-			//	if err := val.Decode(&res); err != nil {
-			//		panic(err)
-			//	}
-			//	r.Set(key.Value, res)
-			//	continue
-			//}
-			if val.Kind == yaml.SequenceNode {
-				values := make([]string, 0, len(value.Content))
-				for i := range val.Content {
-					v := val.Content[i]
-					if v.Kind != yaml.ScalarNode {
-						return fmt.Errorf("行%d: 不支持的数据类型", v.Line)
-					}
-					values = append(values, v.Value)
-				}
-				r.Set(key.Value, values)
-				continue
+			v, err := decodeFieldValue(val)
+			if err != nil {
+				return err
 			}
-
-			if val.Kind != yaml.ScalarNode {
-				return fmt.Errorf("行%d: 不支持的数据类型", val.Line)
-			}
-
-			r.Set(key.Value, val.Value)
+			r.Set(key.Value, v)
+			o.positions[key.Value] = fieldPosition{line: key.Line, column: key.Column}
 		}
 
 	case yaml.SequenceNode:
@@ -104,6 +158,7 @@ func (o *OrderFieldMap) UnmarshalYAML(value *yaml.Node) error {
 				v = valSplit[1]
 			}
 			r.Set(k, v)
+			o.positions[k] = fieldPosition{line: val.Line, column: val.Column}
 		}
 
 	default:
@@ -113,18 +168,115 @@ func (o *OrderFieldMap) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// decodeFieldValue 按YAML原生类型解析变量值, 元素均为字符串的列表解析为[]string, 嵌套Map解析为map[string]interface{}
+func decodeFieldValue(node *yaml.Node) (interface{}, error) {
+	if node.Kind == yaml.SequenceNode {
+		values := make([]string, 0, len(node.Content))
+		for _, v := range node.Content {
+			if v.Kind != yaml.ScalarNode || v.Tag != "!!str" {
+				values = nil
+				break
+			}
+			values = append(values, v.Value)
+		}
+		if values != nil {
+			return values, nil
+		}
+	}
+
+	return decodeFieldNode(node)
+}
+
+// decodeFieldNode 递归解析YAML节点, Map的key统一转换为字符串
+func decodeFieldNode(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return decodeFieldNode(node.Content[0])
+	case yaml.AliasNode:
+		return decodeFieldNode(node.Alias)
+	case yaml.SequenceNode:
+		res := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			v, err := decodeFieldNode(item)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = v
+		}
+		return res, nil
+	case yaml.MappingNode:
+		res := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				if err := mergeFieldNode(res, val); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			v, err := decodeFieldNode(val)
+			if err != nil {
+				return nil, err
+			}
+			res[key.Value] = v
+		}
+		return res, nil
+	default:
+		return decodeFieldScalar(node)
+	}
+}
+
+// mergeFieldNode 处理 `<<` 合并键, 已存在的key不会被覆盖
+func mergeFieldNode(res map[string]interface{}, node *yaml.Node) error {
+	sources := []*yaml.Node{node}
+	if node.Kind == yaml.SequenceNode {
+		sources = node.Content
+	}
+	for _, source := range sources {
+		v, err := decodeFieldNode(source)
+		if err != nil {
+			return err
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("行: %d, 列: %d, 合并键的值必须为Map", source.Line, source.Column)
+		}
+		for k, item := range m {
+			if _, ok = res[k]; !ok {
+				res[k] = item
+			}
+		}
+	}
+	return nil
+}
+
+// decodeFieldScalar 解析标量, 转换为字符串后与原文不一致的数字、布尔值保留原文,
+// 避免 `7.10`、`18.0`、`0755` 等版本号及权限值被转换为 `7.1`、`18`、`493`
+func decodeFieldScalar(node *yaml.Node) (interface{}, error) {
+	var res interface{}
+	if err := node.Decode(&res); err != nil {
+		return nil, fmt.Errorf("行: %d, 列: %d, 解析变量值失败: %w", node.Line, node.Column, err)
+	}
+	switch res.(type) {
+	case nil, string:
+		return res, nil
+	}
+	if fmt.Sprint(res) != node.Value {
+		return node.Value, nil
+	}
+	return res, nil
+}
+
 func (o *OrderFieldMap) Get(key string) (res any, ok bool) {
 	v, b := o.m.Get(key)
 	if !b || v == nil {
 		return "", b
 	}
 
-	if res, ok = v.(string); ok {
-		return
-	}
-
-	res, ok = v.([]string)
-	return
+	return v, b
 }
 
 func (o *OrderFieldMap) Set(key string, info any) {
@@ -133,6 +285,25 @@ func (o *OrderFieldMap) Set(key string, info any) {
 
 func (o *OrderFieldMap) Delete(key string) {
 	o.m.Delete(key)
+	delete(o.positions, key)
+}
+
+// position 获取键在YAML中的行与列, 未知时为0
+func (o *OrderFieldMap) position(key string) (line, column int) {
+	pos := o.positions[key]
+	return pos.line, pos.column
+}
+
+// copyPosition 复制src中键的位置
+func (o *OrderFieldMap) copyPosition(src *OrderFieldMap, key string) {
+	pos, ok := src.positions[key]
+	if !ok {
+		return
+	}
+	if o.positions == nil {
+		o.positions = make(map[string]fieldPosition)
+	}
+	o.positions[key] = pos
 }
 
 func (o *OrderFieldMap) Keys() []string {
@@ -153,6 +324,12 @@ type OrderRemoteVarInfoMap struct {
 	m *orderedmap.OrderedMap
 }
 
+func NewOrderRemoteVarInfoMap() *OrderRemoteVarInfoMap {
+	return &OrderRemoteVarInfoMap{
+		m: orderedmap.New(),
+	}
+}
+
 func (o *OrderRemoteVarInfoMap) UnmarshalYAML(value *yaml.Node) error {
 	if value.Kind != yaml.MappingNode {
 		return errors.New(fmt.Sprintf("行: %d, 列: %d, 错误的数据类型", value.Line, value.Column))
@@ -224,8 +401,43 @@ func (s *SupportRemoteReqType) ToLower() {
 const (
 	SupportRemoteReqTypeHttp  SupportRemoteReqType = "http"
 	SupportRemoteReqTypeHttps SupportRemoteReqType = "https"
+	// SupportRemoteReqTypeFile 本地文件, url为文件路径, 相对路径相对于模板文件所在目录
+	SupportRemoteReqTypeFile SupportRemoteReqType = "file"
+	// SupportRemoteReqTypeGit 本地git仓库, url为仓库路径, 响应数据为仓库元数据(GitRepositoryInfo)
+	SupportRemoteReqTypeGit SupportRemoteReqType = "git"
+	// SupportRemoteReqTypeGraphql graphql接口, 响应中的data为响应数据, errors不为空时视为请求失败
+	SupportRemoteReqTypeGraphql SupportRemoteReqType = "graphql"
+	// SupportRemoteReqTypeMaven maven仓库版本查询, url为仓库地址(支持本地目录及file://), 默认为maven中央仓库
+	SupportRemoteReqTypeMaven SupportRemoteReqType = "maven"
 )
 
+// ByteSize 字节大小, 支持数值及带单位的字符串: 1024, 512KB, 10MB, 1GB(1KB=1024B)
+type ByteSize int64
+
+func (b *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.ScalarNode {
+		return fmt.Errorf("行: %d, 列: %d, 错误的大小配置", value.Line, value.Column)
+	}
+
+	str := strings.ToUpper(strings.TrimSpace(value.Value))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "B"), "I")
+	unit := int64(1)
+	for i, u := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(str, u) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, u))
+			unit = int64(1) << (10 * (i + 1))
+			break
+		}
+	}
+
+	size, err := strconv.ParseFloat(str, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("行: %d, 列: %d, 错误的大小配置: %s", value.Line, value.Column, value.Value)
+	}
+	*b = ByteSize(size * float64(unit))
+	return nil
+}
+
 // HttpUploadFileFormInfo http文件上传表单内容
 type HttpUploadFileFormInfo struct {
 	// Files 文件
@@ -246,18 +458,42 @@ type RemoteVarInfo struct {
 	Headers *OrderFieldMap `yaml:"headers,omitempty"`
 	// RequestUploadFiles 请求上传文件信息
 	RequestUploadFiles *HttpUploadFileFormInfo `yaml:"requestUploadFiles,omitempty"`
-	// RequestParams 请求参数
+	// RequestParams 请求参数, 作为查询参数发送
 	RequestParams *OrderFieldMap `yaml:"requestParams,omitempty"`
 	// RequestFormData 表单请求数据
 	RequestFormData *OrderFieldMap `yaml:"requestFormData,omitempty"`
 	// RequestBody 请求身体
 	RequestBody string `yaml:"requestBody,omitempty"`
-	// ResponseJudge 响应结果判断, 模板返回true/string, true: 正确, 其他: 错误信息
+	// ResponseJudge 响应结果判断, 模板返回true/string, true: 正确, 其他: 错误信息. graphql类型未配置时errors不为空即失败, 可通过 .this.Data.Errors 自行判断
 	ResponseJudge string `yaml:"responseJudge,omitempty"`
-	// ResponseParser 内置响应数据解析器: json | text | base64 | hex
+	// ResponseParser 内置响应数据解析器: json | yaml | text | base64 | hex
 	ResponseParser string `yaml:"responseParser,omitempty"`
 	// PostResponseParser 内置解析器无法满足时使用的自定义响应解析器
 	PostResponseParser string `yaml:"postResponseParser,omitempty"`
+	// Query graphql查询语句
+	Query string `yaml:"query,omitempty"`
+	// OperationName graphql操作名称
+	OperationName string `yaml:"operationName,omitempty"`
+	// Variables graphql变量
+	Variables *OrderFieldMap `yaml:"variables,omitempty"`
+	// Artifact maven构件坐标, 格式: group:artifact
+	Artifact string `yaml:"artifact,omitempty"`
+	// VersionConstraint maven版本约束(semver), 例: ^3.0.0, 2.3.0.RELEASE等非semver格式的版本按maven版本规则转换后匹配
+	VersionConstraint string `yaml:"versionConstraint,omitempty"`
+	// MaxSize 响应内容最大字节数, 支持单位: 10MB, 512KB. 与sha256、sha512等校验配置一样, git类型不支持
+	MaxSize ByteSize `yaml:"maxSize,omitempty"`
+	// Sha256 期望的sha256摘要(hex)
+	Sha256 string `yaml:"sha256,omitempty"`
+	// Sha512 期望的sha512摘要(hex)
+	Sha512 string `yaml:"sha512,omitempty"`
+	// Sha256Url sha256摘要文件地址, 未配置sha256时使用
+	Sha256Url string `yaml:"sha256Url,omitempty"`
+	// Sha512Url sha512摘要文件地址, 未配置sha512时使用
+	Sha512Url string `yaml:"sha512Url,omitempty"`
+	// Session 会话名称, 相同会话的动态变量共享cookie, 可用于先登录后请求
+	Session string `yaml:"session,omitempty"`
+	// Proxy 代理配置, 优先级高于全局代理配置
+	Proxy *ProxyConfig `yaml:"proxy,omitempty"`
 	// SkipHttpsVerifyCert 跳过https的证书认证
 	SkipHttpsVerifyCert bool `yaml:"skipHttpsVerifyCert,omitempty"`
 	// Req 请求接口
@@ -272,13 +508,11 @@ type RemoteVarParser struct {
 	*RemoteVarInfo
 	line   int
 	column int
+	// baseDir 变量所在模板文件的目录
+	baseDir string
 }
 
 func (d *RemoteVarParser) Parse(data map[string]interface{}, thisInfo *ThisInfo, p *Parser) (err error) {
-	if d.Url == "" {
-		return errors.New(fmt.Sprintf("行: %d, 列: %d, 缺失url(请求路径)", d.line, d.column))
-	}
-
 	thisInfo.Data = d.RemoteVarInfo
 
 	if d.Type, _, err = getStrByTemplate(d.Type, data, thisInfo); err != nil {
@@ -287,6 +521,24 @@ func (d *RemoteVarParser) Parse(data map[string]interface{}, thisInfo *ThisInfo,
 
 	d.Type.ToLower()
 
+	if d.Url == "" && d.Type != SupportRemoteReqTypeMaven {
+		return errors.New(fmt.Sprintf("行: %d, 列: %d, 缺失url(请求路径)", d.line, d.column))
+	}
+
+	if d.Proxy != nil {
+		for _, proxyField := range []*string{&d.Proxy.Url, &d.Proxy.Username, &d.Proxy.Password} {
+			if *proxyField, _, err = getStrByTemplate(*proxyField, data, thisInfo); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, checksum := range []*string{&d.Sha256, &d.Sha512, &d.Sha256Url, &d.Sha512Url} {
+		if *checksum, _, err = getStrByTemplate(*checksum, data, thisInfo); err != nil {
+			return err
+		}
+	}
+
 	d.Url, _, err = getStrByTemplate(d.Url, data, thisInfo)
 	if err != nil {
 		return
@@ -301,7 +553,23 @@ func (d *RemoteVarParser) Parse(data map[string]interface{}, thisInfo *ThisInfo,
 	case SupportRemoteReqTypeHttp:
 		fallthrough
 	case SupportRemoteReqTypeHttps:
-		if err := createHttpRequestByVar(d.RemoteVarInfo, data, thisInfo, p); err != nil {
+		if err := createHttpRequestByVar(d, data, thisInfo, p); err != nil {
+			return err
+		}
+	case SupportRemoteReqTypeFile:
+		if err := createFileRequestByVar(d, data, thisInfo, p); err != nil {
+			return err
+		}
+	case SupportRemoteReqTypeGit:
+		if err := createGitRequestByVar(d, data, thisInfo, p); err != nil {
+			return err
+		}
+	case SupportRemoteReqTypeGraphql:
+		if err := createGraphqlRequestByVar(d, data, thisInfo, p); err != nil {
+			return err
+		}
+	case SupportRemoteReqTypeMaven:
+		if err := createMavenRequestByVar(d, data, thisInfo, p); err != nil {
 			return err
 		}
 	default:
@@ -327,127 +595,11 @@ type ProjectComments struct {
 	RemoteVars map[string]string `yaml:"remoteVars,omitempty"`
 }
 
-var DefaultShellConfig = &ShellConfig{}
-
-func init() {
-	if runtime.GOOS == "windows" {
-		DefaultShellConfig.current = "cmd.exe /c"
-	} else {
-		DefaultShellConfig.current = "bash -c"
-	}
-}
-
-type ShellConfig struct {
-	current string `yaml:"current,omitempty"`
-}
-
-func (s *ShellConfig) Exec() {
-}
-
-func (s *ShellConfig) UnmarshalYAML(value *yaml.Node) error {
-	if value.Tag == "!!str" {
-		s.current = value.Value
-		return nil
-	}
-
-	if value.Kind != yaml.MappingNode {
-		return errors.New("不支持的shell配置类型")
-	}
-
-	currentGoos := "unix"
-	if runtime.GOOS == "windows" {
-		currentGoos = "windows"
-	}
-
-	contents := value.Content
-	for i := 0; i < len(contents); i += 2 {
-		k := contents[i]
-		if k.Tag != "!!str" {
-			continue
-		}
-
-		if strings.ToLower(k.Value) == currentGoos {
-			s.current = strings.TrimSpace(contents[i+1].Value)
-		}
-
-	}
-
-	if len(s.current) == 0 {
-		s.current = DefaultShellConfig.current
-	}
-
-	return nil
-}
-
-type ExecuteInfo struct {
-	Post []string `yaml:"post,omitempty"`
-	Pre  []string `yaml:"pre,omitempty"`
-}
-
-func (e *ExecuteInfo) ExecPre(p *Parser, shell string, data map[string]interface{}, thisInfo *ThisInfo) error {
-	return e.execCommands(e.Pre, p, shell, data, thisInfo)
-}
-
-func (e *ExecuteInfo) ExecPost(p *Parser, shell string, data map[string]interface{}, thisInfo *ThisInfo) error {
-	return e.execCommands(e.Post, p, shell, data, thisInfo)
-}
-
-func (e *ExecuteInfo) execCommands(commands []string, p *Parser, shell string, data map[string]interface{}, thisInfo *ThisInfo) error {
-	if len(commands) == 0 {
-		return nil
-	}
-
-	env := os.Environ()
-	if p.TemplateInfo.Envs != nil && p.TemplateInfo.Envs.m != nil {
-		for _, k := range p.TemplateInfo.Envs.Keys() {
-			v, ok := p.TemplateInfo.Envs.Get(k)
-			if !ok {
-				continue
-			}
-
-			if val, ok := v.(string); !ok {
-				continue
-			} else {
-				env = append(env, fmt.Sprintf("%s=%s", k, val))
-			}
-		}
-	}
-
-	var err error
-	for i := range commands {
-		command := commands[i]
-		if command, _, err = getStrByTemplate(command, data, thisInfo); err != nil {
-			return err
-		}
-
-		shellSplit := strings.Split(shell, " ")
-		shellScript := shellSplit[0]
-		cmdArgs := make([]string, 0)
-		if len(shellSplit) > 1 {
-			cmdArgs = append(cmdArgs, shellSplit[1:]...)
-			cmdArgs = append(cmdArgs, command)
-		}
-
-		marshal, _ := json.Marshal(env)
-		logCommand := strings.ReplaceAll(command, "\"", "\\\"")
-		p.LogWithPrevBlockName("\ncommand => %s \"%s\"\nenv=>%s\noutput=>", shell, logCommand, marshal)
-		cmd := exec.Command(shellScript, cmdArgs...)
-		cmd.Stdout = p.bufferWriter
-		cmd.Stderr = p.bufferWriter
-		cmd.Env = env
-		cmd.Dir = p.WorkerPath
-		if err = cmd.Run(); err != nil {
-			return err
-		}
-
-		_ = p.bufferWriter.Flush()
-	}
-	return nil
-}
-
 type ProjectTemplateInfo struct {
 	// Import 导入
 	Import []string `yaml:"import,omitempty"`
+	// Inputs 模板声明的输入参数, 在解析envs之前校验, 模板中通过 `.Inputs` 获取
+	Inputs []*InputInfo `yaml:"inputs,omitempty"`
 	// Env 环境变量
 	Envs *OrderFieldMap `yaml:"envs,omitempty"`
 	// Vars 变量
@@ -462,4 +614,36 @@ type ProjectTemplateInfo struct {
 	Executes *ExecuteInfo `yaml:"executes,omitempty"`
 	// Shell 当前shell环境, 默认 `bash -c`
 	Shell ShellConfig `yaml:"shell,omitempty"`
+	// Proxy 全局代理配置, 作用于导入的远程模板及所有未单独配置代理的动态变量
+	Proxy *ProxyConfig `yaml:"proxy,omitempty"`
+	// Profiles 配置集, 通过名称启用, 覆盖envs、vars、remoteVars与templates
+	Profiles map[string]*ProfileInfo `yaml:"profiles,omitempty"`
+	// Requires 执行命令前需要存在的可执行文件, 在解析envs、vars、remoteVars及执行任何命令之前检查,
+	// 此时变量尚未解析, 模板中应使用 .Inputs 及工程信息
+	Requires []*RequireInfo `yaml:"requires,omitempty"`
+	// Strict 严格模式, 模板中获取Map中不存在的键、获取未定义的envs、vars、remoteVars时报错, 导入的模板启用时同样生效
+	Strict bool `yaml:"strict,omitempty"`
+}
+
+// fillRemoteVarBaseDir 为未设置目录的动态变量设置所在模板文件的目录
+func (t *ProjectTemplateInfo) fillRemoteVarBaseDir(dir string) {
+	fillRemoteVarMapBaseDir(t.RemoteVars, dir)
+	for _, profile := range t.Profiles {
+		if profile != nil {
+			fillRemoteVarMapBaseDir(profile.RemoteVars, dir)
+		}
+	}
+}
+
+func fillRemoteVarMapBaseDir(remoteVars *OrderRemoteVarInfoMap, dir string) {
+	if remoteVars == nil || remoteVars.m == nil {
+		return
+	}
+
+	for _, k := range remoteVars.Keys() {
+		v, _ := remoteVars.Get(k)
+		if v != nil && v.baseDir == "" {
+			v.baseDir = dir
+		}
+	}
 }
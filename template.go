@@ -9,6 +9,12 @@ import (
 
 var textTemplate *template.Template
 
+// strictTextTemplate 严格模式使用的模板, 获取Map中不存在的键时报错
+var strictTextTemplate *template.Template
+
+// textTemplateFuncs 模板方法
+var textTemplateFuncs template.FuncMap
+
 const (
 	writeSplit    = "_._^__^_._"
 	writeSplitLen = len(writeSplit)
@@ -97,7 +103,30 @@ func init() {
 	funcMap["remoteVarResponse"] = templateFnRemoteVarResponse
 	funcMap["writeBytes"] = templateFnWriteBytes
 	funcMap["pathRange"] = templateFnPathRange
+	textTemplateFuncs = funcMap
 	textTemplate = template.New("base").Funcs(funcMap)
+	strictTextTemplate = template.New("strict").Funcs(funcMap).Option("missingkey=error")
+}
+
+// baseTemplate 按是否为严格模式获取模板
+func baseTemplate(thisInfo *ThisInfo) *template.Template {
+	if thisInfo.strict {
+		return strictTextTemplate
+	}
+	return textTemplate
+}
+
+// executeError 执行模板失败时优先返回模板方法设置的错误, 例: 严格模式下获取未定义的变量
+func executeError(err error, thisInfo *ThisInfo) error {
+	if thisErr := thisInfo.error(); thisErr != nil {
+		return thisErr
+	}
+	return err
+}
+
+// rangeTemplate 将templates中的range表达式转换为模板
+func rangeTemplate(expr string) string {
+	return `{{ pathRange .this (` + strings.TrimSpace(expr) + ") }}"
 }
 
 type AnyString interface{ ~string }
@@ -108,14 +137,14 @@ func getStrByTemplate[K AnyString](str K, data map[string]interface{}, thisInfo
 	if len(_str) == 0 {
 		return str, nil, nil
 	}
-	parse, err := textTemplate.Parse(_str)
+	parse, err := baseTemplate(thisInfo).Parse(_str)
 	if err != nil {
 		return "", nil, err
 	}
 
 	buffer := &bytes.Buffer{}
 	if err = parse.Execute(buffer, data); err != nil {
-		return "", nil, err
+		return "", nil, executeError(err, thisInfo)
 	}
 
 	err = thisInfo.error()
@@ -127,14 +156,14 @@ func getStrByTemplate[K AnyString](str K, data map[string]interface{}, thisInfo
 }
 
 func getBytesByTemplate(str string, data map[string]interface{}, thisInfo *ThisInfo) ([]byte, interface{}, error) {
-	parse, err := textTemplate.Parse(str)
+	parse, err := baseTemplate(thisInfo).Parse(str)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	buffer := &bytes.Buffer{}
 	if err = parse.Execute(buffer, data); err != nil {
-		return nil, nil, err
+		return nil, nil, executeError(err, thisInfo)
 	}
 
 	err = thisInfo.error()
@@ -2,6 +2,7 @@ package templateparser
 
 import (
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -26,6 +27,8 @@ type ThisInfo struct {
 	returnData interface{}
 	// cacheDirPath 缓存目录
 	cacheDirPath string
+	// strict 严格模式, 获取未定义的envs、vars、remoteVars时报错
+	strict bool
 }
 
 // getReturnData 获取返回值
@@ -54,26 +57,48 @@ func (t *ThisInfo) Error(errStr string) string {
 	return ""
 }
 
+// undefined 严格模式下记录未定义变量的错误
+func (t *ThisInfo) undefined(section varSection, name string) {
+	if t.strict && t.err == nil {
+		t.err = fmt.Errorf("%s[%s]未定义", section, name)
+	}
+}
+
 // Env 获取环境变量
 func (t *ThisInfo) Env(name string) string {
-	v, _ := t.templateData.Envs.Get(name)
-	str, ok := v.(string)
-	if !ok {
+	if t.templateData.Envs == nil || t.templateData.Envs.m == nil {
+		t.undefined(varSectionEnvs, name)
 		return ""
 	}
-	return str
+	v, ok := t.templateData.Envs.Get(name)
+	if !ok {
+		t.undefined(varSectionEnvs, name)
+	}
+	return stringifyFieldValue(v)
 }
 
 // Var 获取变量
 func (t *ThisInfo) Var(name string) any {
-	v, _ := t.templateData.Vars.Get(name)
+	if t.templateData.Vars == nil || t.templateData.Vars.m == nil {
+		t.undefined(varSectionVars, name)
+		return nil
+	}
+	v, ok := t.templateData.Vars.Get(name)
+	if !ok {
+		t.undefined(varSectionVars, name)
+	}
 	return v
 }
 
 // RemoteVar 获取远程变量
 func (t *ThisInfo) RemoteVar(name string) *RemoteVarInfo {
+	if t.templateData.RemoteVars == nil || t.templateData.RemoteVars.m == nil {
+		t.undefined(varSectionRemoteVars, name)
+		return nil
+	}
 	v, b := t.templateData.RemoteVars.Get(name)
-	if !b {
+	if !b || v == nil {
+		t.undefined(varSectionRemoteVars, name)
 		return nil
 	}
 	return v.RemoteVarInfo